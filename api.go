@@ -0,0 +1,176 @@
+package main
+
+import (
+	"OpenDDNS/internal/history"
+	"OpenDDNS/internal/logger"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// API 控制操作，由主循环在没有进行中的同步时执行
+const (
+	actionRound  = "round"
+	actionPush   = "push"
+	actionPause  = "pause"
+	actionResume = "resume"
+)
+
+// history 接口默认返回的事件数
+const defaultHistoryLimit = 50
+
+// apiServer 提供状态和控制接口，token 为空时不校验，readOnly 时不注册操作接口
+type apiServer struct {
+	token    string
+	readOnly bool
+	status   *daemonStatus
+	control  chan<- string
+}
+
+// register 在 mux 上以 prefix（如 /api/v1）注册 API 路由
+func (a *apiServer) register(mux *http.ServeMux, prefix string) {
+	routes := map[string]http.HandlerFunc{
+		"GET " + prefix + "/status":  a.handleStatus,
+		"GET " + prefix + "/records": a.handleRecords,
+		"GET " + prefix + "/sources": a.handleSources,
+		"GET " + prefix + "/history": a.handleHistory,
+	}
+	if !a.readOnly {
+		routes["POST "+prefix+"/round"] = a.handleRound
+		routes["POST "+prefix+"/records/{name}/push"] = a.handleRecordAction(actionPush)
+		routes["POST "+prefix+"/records/{name}/pause"] = a.handleRecordAction(actionPause)
+		routes["POST "+prefix+"/records/{name}/resume"] = a.handleRecordAction(actionResume)
+	}
+	for pattern, handler := range routes {
+		mux.Handle(pattern, a.authorize(handler))
+	}
+}
+
+// authorize 校验 Authorization: Bearer <token>
+func (a *apiServer) authorize(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.token == "" {
+			next(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="openddns"`)
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (a *apiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.status.snapshot())
+}
+
+func (a *apiServer) handleRecords(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.status.snapshot().Records)
+}
+
+func (a *apiServer) handleSources(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.status.snapshot().Sources)
+}
+
+// handleHistory 返回最近的事件，新的在前；支持 limit、record、event（逗号分隔）、since、until 参数
+func (a *apiServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	f := history.Filter{Record: query.Get("record"), Limit: defaultHistoryLimit}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		f.Limit = n
+	}
+	if v := query.Get("event"); v != "" {
+		f.Events = strings.Split(v, ",")
+	}
+	now := time.Now()
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := query.Get(p.name); v != "" {
+			t, err := history.ParseTime(v, now)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, p.name+": "+err.Error())
+				return
+			}
+			*p.dst = t
+		}
+	}
+	entries, err := a.status.recentEvents(f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []history.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (a *apiServer) handleRound(w http.ResponseWriter, r *http.Request) {
+	a.submit(w, actionRound)
+}
+
+// handleRecordAction 对路径中指定的记录执行操作
+func (a *apiServer) handleRecordAction(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if name := r.PathValue("name"); name != a.status.recordName() {
+			writeError(w, http.StatusNotFound, "unknown record "+strconv.Quote(name))
+			return
+		}
+		if action == actionPush && a.status.snapshot().Records[0].Paused {
+			writeError(w, http.StatusConflict, "record is paused, resume it first")
+			return
+		}
+		a.submit(w, action)
+	}
+}
+
+// submit 将操作交给主循环，队列已满时返回 503
+func (a *apiServer) submit(w http.ResponseWriter, action string) {
+	select {
+	case a.control <- action:
+		logger.Info("API requested %s.", action)
+		writeJSON(w, http.StatusAccepted, map[string]string{"action": action, "status": "accepted"})
+	default:
+		writeError(w, http.StatusServiceUnavailable, "too many pending actions, try again later")
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+// applyControl 执行 API 操作，返回是否需要立即同步一轮
+func (s *syncState) applyControl(action string) bool {
+	log := recordLogger(s.cfg)
+	switch action {
+	case actionRound:
+		return true
+	case actionPush:
+		// 忽略上次推送的缓存，向服务商重新核对并写入
+		s.lastIP = ""
+		return true
+	case actionPause:
+		s.paused = true
+		s.status.setPaused(true)
+		log.Info("Record paused via API.")
+		return false
+	case actionResume:
+		s.paused = false
+		s.status.setPaused(false)
+		log.Info("Record resumed via API.")
+		return true
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testAPIToken = "0123456789abcdef"
+
+// newTestAPI 返回按 httpCfg 注册路由的 handler、主循环状态和控制队列
+func newTestAPI(t *testing.T, httpCfg config.HTTPConfig) (http.Handler, *syncState, chan string) {
+	t.Helper()
+	cfg := &config.Config{Provider: "cloudflare", Domain: "example.com", Subdomain: "home", UpdateIntervalMinutes: 5, HTTP: httpCfg}
+	status := newDaemonStatus(cfg, false)
+	control := make(chan string, 1)
+	handler, err := newHTTPHandler(httpCfg, status, control)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler, &syncState{cfg: cfg, status: status}, control
+}
+
+// serve 发送请求并返回响应码，token 为空时不带 Authorization
+func serve(handler http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIAuthorization(t *testing.T) {
+	handler, _, _ := newTestAPI(t, config.HTTPConfig{Listen: "127.0.0.1:0", API: true, Token: testAPIToken})
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong-token-0123456", http.StatusUnauthorized},
+		{"token prefix", "Bearer " + testAPIToken[:len(testAPIToken)-1], http.StatusUnauthorized},
+		{"token with suffix", "Bearer " + testAPIToken + "x", http.StatusUnauthorized},
+		{"wrong scheme", "Basic " + testAPIToken, http.StatusUnauthorized},
+		{"lowercase scheme", "bearer " + testAPIToken, http.StatusUnauthorized},
+		{"valid", "Bearer " + testAPIToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/status", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestAPIPausePush(t *testing.T) {
+	handler, state, control := newTestAPI(t, config.HTTPConfig{Listen: "127.0.0.1:0", API: true, Token: testAPIToken})
+	const record = "/api/v1/records/home.example.com"
+	// step 发送请求，被接受时像主循环一样取出并执行操作
+	step := func(path string, want int) {
+		t.Helper()
+		rec := serve(handler, http.MethodPost, path, testAPIToken)
+		if rec.Code != want {
+			t.Fatalf("POST %s = %d, want %d: %s", path, rec.Code, want, rec.Body)
+		}
+		if want == http.StatusAccepted {
+			state.applyControl(<-control)
+		}
+	}
+
+	step(record+"/pause", http.StatusAccepted)
+	if !state.paused || !state.status.snapshot().Records[0].Paused {
+		t.Fatal("record not paused")
+	}
+	step(record+"/push", http.StatusConflict)
+	step(record+"/resume", http.StatusAccepted)
+	if state.paused {
+		t.Fatal("record still paused")
+	}
+	state.lastIP = "203.0.113.7"
+	step(record+"/push", http.StatusAccepted)
+	if state.lastIP != "" {
+		t.Error("push did not clear the cached IP")
+	}
+	step("/api/v1/records/other.example.com/push", http.StatusNotFound)
+
+	// 上一个操作还在队列中、主循环忙于同步时拒绝新的操作
+	if rec := serve(handler, http.MethodPost, "/api/v1/round", testAPIToken); rec.Code != http.StatusAccepted {
+		t.Fatalf("POST round = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	if rec := serve(handler, http.MethodPost, "/api/v1/round", testAPIToken); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("POST round with a full queue = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestAPIReadOnly(t *testing.T) {
+	handler, _, control := newTestAPI(t, config.HTTPConfig{Listen: "127.0.0.1:0", API: true, Token: testAPIToken, ReadOnly: true})
+	for _, path := range []string{
+		"/api/v1/round",
+		"/api/v1/records/home.example.com/push",
+		"/api/v1/records/home.example.com/pause",
+		"/api/v1/records/home.example.com/resume",
+	} {
+		if rec := serve(handler, http.MethodPost, path, testAPIToken); rec.Code < 400 {
+			t.Errorf("POST %s = %d, want rejected", path, rec.Code)
+		}
+	}
+	if len(control) != 0 {
+		t.Errorf("read-only API queued %q", <-control)
+	}
+	if rec := serve(handler, http.MethodGet, "/api/v1/records", testAPIToken); rec.Code != http.StatusOK {
+		t.Errorf("GET records = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
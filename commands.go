@@ -0,0 +1,562 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/history"
+	ipfetcher "OpenDDNS/internal/ip_fetcher"
+	"OpenDDNS/internal/logger"
+	"OpenDDNS/internal/provider"
+	"OpenDDNS/internal/secret"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// command 一个子命令
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+func commandList() []command {
+	return []command{
+		{"run", "Run as a daemon, detect IP and update DNS periodically (default)", cmdRun},
+		{"once", "Run a single detection and update round, then exit", cmdOnce},
+		{"plan", "Show the DNS changes that would be made, without applying them", cmdPlan},
+		{"check", "Validate the config file and test provider credentials", cmdCheck},
+		{"ip", "Query every IP source and show the vote", cmdIP},
+		{"records", "Query or delete records on the provider (list, get, delete)", cmdRecords},
+		{"history", "Show or export the update history (table, csv, json)", cmdHistory},
+		{"init", "Create a config file interactively", cmdInit},
+		{"secret", "Generate a key file or encrypt a config value (keygen, encrypt)", cmdSecret},
+		{"version", "Print version information", cmdVersion},
+		{"help", "Show this help", cmdHelp},
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: openddns [command] [options]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, cmd := range commandList() {
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	w.Flush()
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'openddns <command> -h' for command options.")
+}
+
+// newFlagSet 创建子命令的参数集，并注册公共的 -c/--config 参数
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var configPath string
+	fs.StringVar(&configPath, "c", "config.yml", "Path to config file")
+	fs.StringVar(&configPath, "config", "config.yml", "Path to config file")
+	return fs, &configPath
+}
+
+// setup 解析参数、加载配置并创建服务商，失败时输出错误
+func setup(fs *flag.FlagSet, configPath *string, args []string) (*config.Config, provider.DNSProvider, bool) {
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, false
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return nil, nil, false
+	}
+	logger.UseStderr()
+	dnsProvider, err := newProvider(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return nil, nil, false
+	}
+	return cfg, dnsProvider, true
+}
+
+// cmdPlan 检测一次公网 IP 并输出将对记录执行的变更，不修改任何记录
+func cmdPlan(args []string) int {
+	fs, configPath := newFlagSet("plan")
+	cfg, dnsProvider, ok := setup(fs, configPath, args)
+	if !ok {
+		return exitFailure
+	}
+	newIP := getMajorityIPWithNetwork(context.Background(), cfg.IPSources, networkTypeFor(cfg.RecordType))
+	if newIP == "" {
+		fmt.Fprintln(os.Stderr, "Error: failed to determine public IP")
+		return exitDetectionFailed
+	}
+	recordType := ipfetcher.DetermineRecordType(newIP, cfg.RecordType)
+	if recordType == "" {
+		fmt.Fprintf(os.Stderr, "Error: invalid IP address format: %s\n", newIP)
+		return exitDetectionFailed
+	}
+	fmt.Printf("Detected public IP: %s (%s)\n", newIP, recordType)
+	changes, err := dnsProvider.PlanRecord(context.Background(), newIP, recordType)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error planning DNS changes:", logger.Redact(err.Error()))
+		return exitProviderFailed
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes. DNS record is up-to-date.")
+		return exitUnchanged
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	fmt.Printf("Plan: %d change(s).\n", len(changes))
+	return exitUnchanged
+}
+
+// cmdCheck 校验配置并通过查询记录测试凭证，一次列出所有问题
+func cmdCheck(args []string) int {
+	fs, configPath := newFlagSet("check")
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	cfg, err := loadConfig(*configPath)
+	var invalid *config.ValidationError
+	if errors.As(err, &invalid) {
+		fmt.Fprintf(os.Stderr, "Config %s has %d problem(s):\n", *configPath, len(invalid.Problems))
+		for _, problem := range invalid.Problems {
+			fmt.Fprintf(os.Stderr, "  ✗ %s\n", problem)
+		}
+		return exitFailure
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailure
+	}
+	logger.UseStderr()
+	fmt.Printf("✓ Config %s is valid: %s.%s via %s\n", *configPath, cfg.Subdomain, cfg.Domain, cfg.Provider)
+	dnsProvider, err := newProvider(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailure
+	}
+	records, err := dnsProvider.ListRecords(context.Background(), cfg.Subdomain, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Provider check failed: %s\n", logger.Redact(err.Error()))
+		return exitProviderFailed
+	}
+	fmt.Printf("✓ Provider credentials OK, %d existing record(s) for %s.%s.\n", len(records), cfg.Subdomain, cfg.Domain)
+	return exitUnchanged
+}
+
+// cmdIP 查询每个 IP 源并输出各自的结果与投票结论
+func cmdIP(args []string) int {
+	fs, configPath := newFlagSet("ip")
+	var network string
+	var verbose, asJSON bool
+	fs.StringVar(&network, "network", "", "Force network type: ipv4, ipv6 (default: derived from record_type, both with -verbose)")
+	fs.BoolVar(&verbose, "verbose", false, "Query every source over IPv4 and IPv6 and show status, latency, raw value and verdict")
+	fs.BoolVar(&asJSON, "json", false, "Print the diagnostics as JSON")
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailure
+	}
+	logger.UseStderr()
+	networks := []string{network}
+	switch {
+	case network != "":
+	case verbose || asJSON:
+		networks = []string{"ipv4", "ipv6"}
+	default:
+		networks = []string{networkTypeFor(cfg.RecordType)}
+	}
+
+	var report []ipReport
+	found := false
+	for _, nw := range networks {
+		results := fetchAll(context.Background(), cfg.IPSources, nw)
+		ip, method := voteIP(results)
+		found = found || ip != ""
+		report = append(report, newIPReport(nw, results, ip, method))
+	}
+
+	switch {
+	case asJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	case verbose:
+		for _, r := range report {
+			printIPReport(r, true)
+		}
+	default:
+		printIPReport(report[0], false)
+	}
+	if !found {
+		return exitDetectionFailed
+	}
+	return exitUnchanged
+}
+
+// ipReport 某一网络类型下各 IP 源的结果与投票结论
+type ipReport struct {
+	Network string           `json:"network"`
+	Sources []ipSourceReport `json:"sources"`
+	Vote    struct {
+		IP     string `json:"ip"`
+		Method string `json:"method"` // none, single, majority, priority
+	} `json:"vote"`
+}
+
+type ipSourceReport struct {
+	Name       string  `json:"name"`
+	StatusCode int     `json:"status_code,omitempty"`
+	LatencyMS  float64 `json:"latency_ms"`
+	Raw        string  `json:"raw,omitempty"`
+	Verdict    string  `json:"verdict,omitempty"`
+	IP         string  `json:"ip,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+func newIPReport(network string, results []ipfetcher.FetchResult, ip, method string) ipReport {
+	r := ipReport{Network: network}
+	if r.Network == "" {
+		r.Network = "auto"
+	}
+	for _, res := range results {
+		src := ipSourceReport{
+			Name:       res.Source,
+			StatusCode: res.StatusCode,
+			LatencyMS:  float64(res.Latency.Microseconds()) / 1000,
+			Raw:        res.Raw,
+			Verdict:    res.Verdict,
+			IP:         res.IP,
+		}
+		if res.Err != nil {
+			src.Error = res.Err.Error()
+		}
+		r.Sources = append(r.Sources, src)
+	}
+	r.Vote.IP = ip
+	r.Vote.Method = method
+	return r
+}
+
+func printIPReport(r ipReport, verbose bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if verbose {
+		fmt.Printf("== %s ==\n", r.Network)
+		fmt.Fprintln(w, "SOURCE\tSTATUS\tLATENCY\tRAW\tVERDICT\tRESULT")
+	} else {
+		fmt.Fprintln(w, "SOURCE\tRESULT")
+	}
+	for _, src := range r.Sources {
+		result := src.IP
+		if src.Error != "" {
+			result = "error: " + src.Error
+		}
+		if verbose {
+			status := "-"
+			if src.StatusCode != 0 {
+				status = fmt.Sprint(src.StatusCode)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%.0fms\t%q\t%s\t%s\n", src.Name, status, src.LatencyMS, src.Raw, src.Verdict, result)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\n", src.Name, result)
+		}
+	}
+	w.Flush()
+	if r.Vote.IP == "" {
+		fmt.Println("Vote: no available IP sources")
+	} else {
+		fmt.Printf("Vote: %s (%s)\n", r.Vote.IP, r.Vote.Method)
+	}
+	if verbose {
+		fmt.Println()
+	}
+}
+
+// cmdRecords 查询或删除服务商上的记录
+func cmdRecords(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "Usage: openddns records <list|get|delete> [options]")
+		return exitFailure
+	}
+	action, args := args[0], args[1:]
+	fs, configPath := newFlagSet("records " + action)
+	var recordType, subdomain string
+	var yes bool
+	fs.StringVar(&recordType, "type", "", "Record type filter, e.g. A, AAAA")
+	switch action {
+	case "list":
+	case "get":
+		fs.StringVar(&subdomain, "name", "", "Subdomain to query (default: subdomain from config)")
+	case "delete":
+		fs.BoolVar(&yes, "yes", false, "Actually delete; without it only the planned deletions are shown")
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown records action: %s\n", action)
+		return exitFailure
+	}
+	cfg, dnsProvider, ok := setup(fs, configPath, args)
+	if !ok {
+		return exitFailure
+	}
+	recordType = strings.ToUpper(recordType)
+
+	if action == "delete" {
+		if recordType == "" {
+			fmt.Fprintln(os.Stderr, "Error: -type is required for delete")
+			return exitFailure
+		}
+		changes, err := dnsProvider.PlanDelete(context.Background(), recordType)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", logger.Redact(err.Error()))
+			return exitProviderFailed
+		}
+		if len(changes) == 0 {
+			fmt.Println("Nothing to delete.")
+			return exitUnchanged
+		}
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		if !yes {
+			fmt.Println("Re-run with -yes to delete these records.")
+			return exitUnchanged
+		}
+		if _, err := dnsProvider.DeleteRecord(context.Background(), recordType); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", logger.Redact(err.Error()))
+			return exitProviderFailed
+		}
+		fmt.Printf("Deleted %d record(s).\n", len(changes))
+		return exitUpdated
+	}
+
+	if action == "get" && subdomain == "" {
+		subdomain = cfg.Subdomain
+	}
+	records, err := dnsProvider.ListRecords(context.Background(), subdomain, recordType)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", logger.Redact(err.Error()))
+		return exitProviderFailed
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tCONTENT\tTTL\tOWNED\tID\tCOMMENT")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%t\t%s\t%s\n", r.Name, r.Type, r.Content, r.TTL, r.Owned, r.ID, r.Comment)
+	}
+	w.Flush()
+	return exitUnchanged
+}
+
+// cmdHistory 按条件查询 history_file 中的历史记录，输出表格或导出 CSV/JSON
+func cmdHistory(args []string) int {
+	fs, configPath := newFlagSet("history")
+	var file, record, events, since, until, format string
+	var limit int
+	fs.StringVar(&file, "file", "", "History file to read (default: history_file from config)")
+	fs.StringVar(&record, "record", "", "Only show this record, e.g. www.example.com")
+	fs.StringVar(&events, "event", "", "Comma-separated events: "+strings.Join(history.Events, ", "))
+	fs.StringVar(&since, "since", "", "Only entries at or after this time, e.g. 2026-01-02, 2026-01-02T15:04:05Z, 24h, 7d")
+	fs.StringVar(&until, "until", "", "Only entries before this time, same formats as -since")
+	fs.IntVar(&limit, "limit", 0, "Only show the newest N entries (0 = all)")
+	fs.StringVar(&format, "format", "table", "Output format: table, csv or json")
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	if file == "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitFailure
+		}
+		if file = historyPath(*configPath, cfg); file == "" {
+			fmt.Fprintln(os.Stderr, "Error: history_file is not set in the config, set it or use -file")
+			return exitFailure
+		}
+	}
+
+	f := history.Filter{Record: record, Limit: limit}
+	if events != "" {
+		for _, e := range strings.Split(events, ",") {
+			e = strings.TrimSpace(e)
+			if !history.ValidEvent(e) {
+				fmt.Fprintf(os.Stderr, "Error: -event: unknown event %q, expected any of: %s\n", e, strings.Join(history.Events, ", "))
+				return exitFailure
+			}
+			f.Events = append(f.Events, e)
+		}
+	}
+	now := time.Now()
+	for _, t := range []struct {
+		flag  string
+		value string
+		dst   *time.Time
+	}{{"since", since, &f.Since}, {"until", until, &f.Until}} {
+		if t.value == "" {
+			continue
+		}
+		parsed, err := history.ParseTime(t.value, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -%s: %v\n", t.flag, err)
+			return exitFailure
+		}
+		*t.dst = parsed
+	}
+	// 跳过损坏行的警告输出到标准错误，不混入导出内容
+	history.SetLogger(func(format string, a ...interface{}) {
+		fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", a...)
+	})
+	entries, err := history.Read(file, f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailure
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		err = history.WriteJSON(os.Stdout, entries)
+	case "csv":
+		err = history.WriteCSV(os.Stdout, entries)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tEVENT\tRECORD\tOLD\tNEW\tLAG\tRECORD_ID\tERROR")
+		for _, e := range entries {
+			lag := "-"
+			if e.LagSeconds > 0 {
+				lag = time.Duration(e.LagSeconds * float64(time.Second)).Round(time.Second).String()
+			}
+			event := e.Event
+			if e.DryRun {
+				event += " (dry-run)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"),
+				event, e.Record, dash(e.OldValue), dash(e.NewValue), lag, dash(e.RecordID), e.Error)
+		}
+		err = w.Flush()
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q, expected table, csv or json\n", format)
+		return exitFailure
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailure
+	}
+	return exitUnchanged
+}
+
+// dash 空值显示为 -
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// cmdSecret 生成密钥文件或加密配置值
+func cmdSecret(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "Usage: openddns secret <keygen|encrypt> [options]")
+		return exitFailure
+	}
+	action, args := args[0], args[1:]
+	fs, configPath := newFlagSet("secret " + action)
+	var keyFile string
+	var force bool
+	fs.StringVar(&keyFile, "key-file", "", "Key file (default: secret_key_file from the config, $"+config.PassphraseEnv+" or "+config.DefaultKeyFile+" next to the config)")
+	if action == "keygen" {
+		fs.BoolVar(&force, "force", false, "Overwrite an existing key file")
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	// 与加载配置时的查找顺序一致：secret_key_file → 口令环境变量 → 配置目录下的 openddns.key
+	defaultKeyFile, configured := config.SecretKeyFile(*configPath)
+
+	switch action {
+	case "keygen":
+		if keyFile == "" {
+			keyFile = defaultKeyFile
+		}
+		if _, err := os.Stat(keyFile); err == nil && !force {
+			fmt.Fprintf(os.Stderr, "Error: %s already exists, use -force to overwrite\n", keyFile)
+			return exitFailure
+		}
+		key, err := secret.GenerateKey()
+		if err == nil {
+			err = os.WriteFile(keyFile, []byte(key+"\n"), 0600)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitFailure
+		}
+		fmt.Printf("Key written to %s. Keep it out of version control and backups of config.yml.\n", keyFile)
+		return exitUnchanged
+	case "encrypt":
+		var key string
+		var err error
+		switch {
+		case keyFile != "":
+			key, err = secret.ReadKeyFile(keyFile)
+		case !configured && os.Getenv(config.PassphraseEnv) != "":
+			key = os.Getenv(config.PassphraseEnv)
+		default:
+			key, err = secret.ReadKeyFile(defaultKeyFile)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitFailure
+		}
+		// 待加密的值取自参数，未提供时从标准输入读取一行，避免出现在 shell 历史中；
+		// 标准输入为终端时不回显，管道输入时直接读取
+		value := fs.Arg(0)
+		if value == "" {
+			if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+				fmt.Fprint(os.Stderr, "Value to encrypt (input hidden): ")
+				b, err := term.ReadPassword(fd)
+				fmt.Fprintln(os.Stderr)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+					return exitFailure
+				}
+				value = strings.TrimRight(string(b), "\r\n")
+			} else {
+				line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				value = strings.TrimRight(line, "\r\n")
+			}
+		}
+		if value == "" {
+			fmt.Fprintln(os.Stderr, "Error: empty value")
+			return exitFailure
+		}
+		encrypted, err := secret.Encrypt(value, key)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitFailure
+		}
+		fmt.Println(encrypted)
+		return exitUnchanged
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown secret action: %s\n", action)
+		return exitFailure
+	}
+}
+
+func cmdVersion(args []string) int {
+	fmt.Printf("OpenDDNS %s (build %s)\n", Version, BuildTime)
+	return exitUnchanged
+}
+
+func cmdHelp(args []string) int {
+	printUsage()
+	return exitUnchanged
+}
@@ -4,6 +4,20 @@ go 1.24.3
 
 require github.com/alibabacloud-go/tea v1.3.9
 
+require golang.org/x/term v0.28.0
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_golang v1.20.5
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.55.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
 require (
 	github.com/alibabacloud-go/alibabacloud-gateway-spi v0.0.5 // indirect
 	github.com/alibabacloud-go/endpoint-util v1.1.0 // indirect
@@ -11,7 +25,9 @@ require (
 	github.com/alibabacloud-go/tea-utils/v2 v2.0.7 // indirect
 	github.com/aliyun/credentials-go v1.4.5 // indirect
 	github.com/clbanning/mxj/v2 v2.7.0 // indirect
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/tjfoc/gmsm v1.4.1 // indirect
+	golang.org/x/sys v0.29.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 )
 
@@ -19,7 +35,7 @@ require (
 	github.com/alibabacloud-go/alidns-20150109/v4 v4.5.10
 	github.com/alibabacloud-go/darabonba-openapi/v2 v2.1.7
 	github.com/alibabacloud-go/debug v1.0.1 // indirect
-	github.com/cloudflare/cloudflare-go v0.115.0 // indirect
+	github.com/cloudflare/cloudflare-go v0.115.0
 	github.com/goccy/go-json v0.10.5 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
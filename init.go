@@ -0,0 +1,508 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/logger"
+	"OpenDDNS/internal/provider"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/term"
+)
+
+// initAnswers init 生成配置时使用的取值，未回答的项保留默认值
+type initAnswers struct {
+	Provider          string
+	Domain            string
+	Subdomain         string
+	RecordType        string
+	CloudflareToken   string
+	CloudflareZoneID  string
+	AliyunKeyID       string
+	AliyunKeySecret   string
+	IPSources         []config.IPSrc
+	CredentialsTested bool
+}
+
+// defaultIPSources 内置的 IPv4 检测来源
+var defaultIPSources = []config.IPSrc{
+	{Name: "bilibili", URL: "https://api.live.bilibili.com/xlive/web-room/v1/index/getIpInfo", Type: "json", JSONPath: "data.addr"},
+	{Name: "cloudflare", URL: "https://www.cloudflare-cn.com/cdn-cgi/trace", Type: "trace"},
+}
+
+func defaultAnswers() initAnswers {
+	return initAnswers{
+		Provider:        "cloudflare",
+		Domain:          "example.com",
+		Subdomain:       "www",
+		RecordType:      "auto",
+		CloudflareToken: "YOUR_CLOUDFLARE_API_TOKEN",
+		AliyunKeyID:     "YOUR_ALIYUN_ACCESS_KEY_ID",
+		AliyunKeySecret: "YOUR_ALIYUN_ACCESS_KEY_SECRET",
+		IPSources:       defaultIPSources,
+	}
+}
+
+// configTemplate init 生成的配置模板
+var configTemplate = template.Must(template.New("config").Funcs(template.FuncMap{
+	"q": strconv.Quote,
+}).Parse(`provider: {{q .Provider}}
+
+domain: {{q .Domain}}
+subdomain: {{q .Subdomain}}
+
+# DNS record type: A (IPv4), AAAA (IPv6), or auto (automatic detection)
+# A: Force IPv4 network access to all APIs
+# AAAA: Force IPv6 network access to all APIs
+# auto: Let system choose the best network path
+record_type: {{q .RecordType}}
+
+# Optional record settings, applied on create and update (unset = keep existing)
+record_options:
+  ttl: 0              # 0 = Cloudflare 60s / Aliyun default
+  # proxied: false    # Cloudflare only
+  comment: ""
+  line: ""            # Aliyun only, e.g. "default"
+  preserve_existing: false
+
+log_level: "info"
+log_file: ""
+log_format: "text"  # text or json
+# Rotate log_file by size/age, 0 = disabled
+log_rotate:
+  max_size_mb: 0
+  rotate_hours: 0
+  max_backups: 0
+  max_age_days: 0
+  compress: false
+# Log outputs, any of: stdout, file, syslog, journald (default: file if log_file is set, else stdout)
+# log_output: ["stdout"]
+# log_syslog:
+#   network: ""     # "" = local syslog, udp or tcp for a remote collector
+#   address: ""     # host:port
+#   tag: "openddns"
+#   facility: "daemon"
+
+# Append-only JSONL history of IP changes and updates, relative to this file; query with: openddns history
+history_file: "history.jsonl"
+
+# Notifications: ip_changed, update_succeeded, update_failed, detection_failed, recovered
+# notify:
+#   - type: webhook
+#     events: ["update_failed", "detection_failed", "recovered"]
+#     url: "https://example.com/hook"
+#     body: '{"text": {{"{{"}}json .Message{{"}}"}}}'
+#   - type: telegram   # also dingtalk, feishu, wecom, slack, discord with url (+ secret for dingtalk/feishu)
+#     bot_token: "${TELEGRAM_BOT_TOKEN}"
+#     chat_id: "123456789"
+#   - type: email      # failures and recoveries immediately, plus a daily digest
+#     digest: "08:00"
+#     smtp:
+#       host: "smtp.example.com"
+#       port: 587        # security: starttls (default), tls (465) or none
+#       username: "ddns@example.com"
+#       password: "${SMTP_PASSWORD}"
+#       from: "OpenDDNS <ddns@example.com>"
+#       to: ["ops@example.com"]
+
+# Seconds to wait for an in-flight update on SIGINT/SIGTERM before exiting
+shutdown_grace_seconds: 30
+
+# Optional built-in HTTP server: "host:port" or "unix:/path"
+# http:
+#   listen: "127.0.0.1:9876"
+#   metrics: true   # Prometheus metrics at /metrics
+#   health: true    # /healthz and /readyz probes
+#   api: false      # /api/v1 status and control API, listen must be loopback or unix
+#   token: ""       # API/dashboard bearer token, at least 16 characters
+#   dashboard: false # web dashboard at /
+#   read_only: false # disable actions in the API and dashboard
+
+# What to do when no address of the record's family is found for N rounds: keep, delete, set
+on_missing:
+  action: "keep"
+  after_rounds: 3
+  # value: "offline.example.com"   # used by "set"
+  # type: "CNAME"                  # used by "set", defaults to record_type (required with auto)
+
+ip_sources:
+{{- range .IPSources}}
+  - name: {{q .Name}}
+    url: {{q .URL}}
+    type: {{q .Type}}
+{{- if .JSONPath}}
+    json_path: {{q .JSONPath}}
+{{- end}}
+{{- end}}
+  # IPv6 sources (uncomment if you need IPv6 DDNS)
+  # - name: "ipify-ipv6"
+  #   url: "https://api64.ipify.org"
+  #   type: "text"
+  # - name: "icanhazip-ipv6"
+  #   url: "https://ipv6.icanhazip.com"
+  #   type: "text"
+
+update_interval_minutes: 5
+
+# Only modify records tagged by OpenDDNS (Cloudflare comment / Aliyun remark)
+ownership:
+  enabled: false
+  owner_id: "default"
+  allow_unowned: false
+
+cloudflare:
+  api_token: {{q .CloudflareToken}}
+  zone_id: {{q .CloudflareZoneID}}
+aliyun:
+  access_key_id: {{q .AliyunKeyID}}
+  access_key_secret: {{q .AliyunKeySecret}}
+  endpoint: "alidns.aliyuncs.com"
+`))
+
+// renderConfig 按模板生成配置文件内容
+func renderConfig(answers initAnswers) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := configTemplate.Execute(&buf, answers); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cmdInit 交互式生成配置文件，-defaults 时不提问直接写入默认配置
+func cmdInit(args []string) int {
+	fs, configPath := newFlagSet("init")
+	var force, defaults bool
+	answers := defaultAnswers()
+	fs.BoolVar(&force, "force", false, "Overwrite an existing config file")
+	fs.BoolVar(&defaults, "defaults", false, "Do not prompt, write a config with default values (combine with the flags below)")
+	fs.StringVar(&answers.Provider, "provider", answers.Provider, "DNS provider for -defaults: cloudflare or aliyun")
+	fs.StringVar(&answers.Domain, "domain", answers.Domain, "Domain for -defaults")
+	fs.StringVar(&answers.Subdomain, "subdomain", answers.Subdomain, "Subdomain for -defaults")
+	fs.StringVar(&answers.RecordType, "record-type", answers.RecordType, "Record type for -defaults: auto, A or AAAA")
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	if _, err := os.Stat(*configPath); err == nil && !force {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists, use -force to overwrite\n", *configPath)
+		return exitFailure
+	}
+
+	if !defaults {
+		w := &wizard{in: bufio.NewReader(os.Stdin), out: os.Stdout, tty: -1}
+		if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+			w.tty = fd
+		}
+		if err := w.run(&answers); err != nil {
+			fmt.Fprintln(os.Stderr, "\nError:", err)
+			return exitFailure
+		}
+	}
+
+	data, err := renderConfig(answers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to render config:", err)
+		return exitFailure
+	}
+	problems, err := validateConfigData(*configPath, data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to validate config:", err)
+		return exitFailure
+	}
+	if len(problems) > 0 && !defaults {
+		// 交互模式只写入通过校验的配置
+		fmt.Fprintln(os.Stderr, "The generated config is invalid, nothing was written:")
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "  ✗ %s\n", p)
+		}
+		return exitFailure
+	}
+	if err := os.WriteFile(*configPath, data, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to write config:", err)
+		return exitFailure
+	}
+	if len(problems) > 0 {
+		fmt.Printf("Config written to %s. Edit the following before running OpenDDNS:\n", *configPath)
+		for _, p := range problems {
+			fmt.Printf("  - %s\n", p)
+		}
+		return exitUnchanged
+	}
+	fmt.Printf("Config written to %s.\n", *configPath)
+	if !answers.CredentialsTested {
+		fmt.Println("Credentials were not verified; run `openddns check` and `openddns plan` before starting.")
+	}
+	return exitUnchanged
+}
+
+// validateConfigData 用与运行时相同的规则校验生成的配置，返回全部问题
+func validateConfigData(configPath string, data []byte) ([]string, error) {
+	// 写到目标目录下的临时文件，使相对路径（如 secret_key_file）按同一目录解析
+	f, err := os.CreateTemp(filepath.Dir(configPath), ".openddns-init-*.yml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	_, err = config.LoadConfig(f.Name())
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+		return verr.Problems, nil
+	}
+	return nil, err
+}
+
+// wizard 在终端上逐项询问配置
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+	tty int // 标准输入为终端时的文件描述符，用于不回显地读取凭证；否则为 -1
+}
+
+// ask 输出提示并读取一行，直接回车时返回默认值
+func (w *wizard) ask(prompt, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", prompt, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", prompt)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", errors.New("unexpected end of input")
+		}
+		return "", err
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+// require 询问必填项，为空时重复询问
+func (w *wizard) require(prompt, def string) (string, error) {
+	for {
+		v, err := w.ask(prompt, def)
+		if err != nil || v != "" {
+			return v, err
+		}
+		fmt.Fprintln(w.out, "  A value is required.")
+	}
+}
+
+// requireSecret 询问必填的凭证，标准输入为终端时不回显输入
+func (w *wizard) requireSecret(prompt string) (string, error) {
+	if w.tty < 0 {
+		return w.require(prompt, "")
+	}
+	for {
+		fmt.Fprintf(w.out, "%s (input hidden): ", prompt)
+		b, err := term.ReadPassword(w.tty)
+		fmt.Fprintln(w.out)
+		if err != nil {
+			return "", err
+		}
+		if v := strings.TrimSpace(string(b)); v != "" {
+			return v, nil
+		}
+		fmt.Fprintln(w.out, "  A value is required.")
+	}
+}
+
+// choose 询问有限选项，忽略大小写，返回选项的规范写法
+func (w *wizard) choose(prompt, def string, options ...string) (string, error) {
+	for {
+		v, err := w.ask(fmt.Sprintf("%s (%s)", prompt, strings.Join(options, "/")), def)
+		if err != nil {
+			return "", err
+		}
+		for _, o := range options {
+			if strings.EqualFold(v, o) {
+				return o, nil
+			}
+		}
+		fmt.Fprintf(w.out, "  Please enter one of: %s\n", strings.Join(options, ", "))
+	}
+}
+
+// confirm 询问是/否
+func (w *wizard) confirm(prompt string, def bool) (bool, error) {
+	d := "y/N"
+	if def {
+		d = "Y/n"
+	}
+	for {
+		v, err := w.ask(fmt.Sprintf("%s [%s]", prompt, d), "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(v) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// run 依次询问服务商、凭证、域名、记录类型和 IP 来源
+func (w *wizard) run(a *initAnswers) error {
+	fmt.Fprintln(w.out, "OpenDDNS config wizard. Press Enter to accept the value in brackets.")
+	fmt.Fprintln(w.out)
+
+	var err error
+	if a.Provider, err = w.choose("DNS provider", a.Provider, "cloudflare", "aliyun"); err != nil {
+		return err
+	}
+	zones, err := w.credentials(a)
+	if err != nil {
+		return err
+	}
+	if err := w.domain(a, zones); err != nil {
+		return err
+	}
+	if a.Subdomain, err = w.require("Subdomain (host label, e.g. www or home)", a.Subdomain); err != nil {
+		return err
+	}
+	if a.RecordType, err = w.choose("Record type", a.RecordType, "auto", "A", "AAAA"); err != nil {
+		return err
+	}
+	return w.sources(a)
+}
+
+// credentials 询问凭证并尝试列出域名验证，失败时可重新输入或跳过
+func (w *wizard) credentials(a *initAnswers) ([]provider.Zone, error) {
+	for {
+		var lister provider.ZoneLister
+		var err error
+		switch a.Provider {
+		case "cloudflare":
+			if a.CloudflareToken, err = w.requireSecret("Cloudflare API token"); err != nil {
+				return nil, err
+			}
+			lister = &provider.Cloudflare{APIToken: a.CloudflareToken}
+		case "aliyun":
+			if a.AliyunKeyID, err = w.require("Aliyun AccessKey ID", ""); err != nil {
+				return nil, err
+			}
+			if a.AliyunKeySecret, err = w.requireSecret("Aliyun AccessKey Secret"); err != nil {
+				return nil, err
+			}
+			lister = &provider.Aliyun{AccessKeyID: a.AliyunKeyID, AccessKeySecret: a.AliyunKeySecret}
+		}
+		logger.SetSecrets(a.CloudflareToken, a.AliyunKeyID, a.AliyunKeySecret)
+		fmt.Fprintln(w.out, "Testing credentials...")
+		zones, err := lister.ListZones(context.Background())
+		if err == nil {
+			a.CredentialsTested = true
+			fmt.Fprintf(w.out, "  ✓ Credentials OK, %d domain(s) available.\n", len(zones))
+			return zones, nil
+		}
+		fmt.Fprintf(w.out, "  ✗ Credential test failed: %s\n", logger.Redact(err.Error()))
+		retry, err := w.confirm("Enter credentials again?", true)
+		if err != nil {
+			return nil, err
+		}
+		if !retry {
+			fmt.Fprintln(w.out, "  Keeping the untested credentials.")
+			return nil, nil
+		}
+	}
+}
+
+// domain 询问域名，凭证可用时列出可选域名并支持按序号选择
+func (w *wizard) domain(a *initAnswers, zones []provider.Zone) error {
+	def := a.Domain
+	if len(zones) > 0 {
+		fmt.Fprintln(w.out, "Available domains:")
+		for i, z := range zones {
+			fmt.Fprintf(w.out, "  %d) %s\n", i+1, z.Name)
+		}
+		def = zones[0].Name
+	}
+	for {
+		v, err := w.require("Domain (name or number)", def)
+		if err != nil {
+			return err
+		}
+		if n, err := strconv.Atoi(v); err == nil && len(zones) > 0 {
+			if n < 1 || n > len(zones) {
+				fmt.Fprintf(w.out, "  Please enter a number between 1 and %d.\n", len(zones))
+				continue
+			}
+			v = zones[n-1].Name
+		}
+		v = strings.TrimSuffix(strings.ToLower(v), ".")
+		a.Domain = v
+		if len(zones) == 0 {
+			return nil
+		}
+		for _, z := range zones {
+			if z.Name == v {
+				if a.Provider == "cloudflare" {
+					a.CloudflareZoneID = z.ID
+				}
+				return nil
+			}
+		}
+		ok, err := w.confirm(fmt.Sprintf("%s is not in the provider account, use it anyway?", v), false)
+		if err != nil || ok {
+			return err
+		}
+	}
+}
+
+// sources 询问是否使用内置 IP 来源，否则逐个录入自定义来源
+func (w *wizard) sources(a *initAnswers) error {
+	fmt.Fprintln(w.out, "Built-in IP sources:")
+	for _, src := range defaultIPSources {
+		fmt.Fprintf(w.out, "  - %s (%s)\n", src.Name, src.URL)
+	}
+	useDefault, err := w.confirm("Use the built-in IP sources?", true)
+	if err != nil || useDefault {
+		return err
+	}
+	a.IPSources = nil
+	fmt.Fprintln(w.out, "Add IP sources, leave the name empty to finish.")
+	for {
+		var src config.IPSrc
+		if src.Name, err = w.ask("Source name", ""); err != nil {
+			return err
+		}
+		if src.Name == "" {
+			if len(a.IPSources) == 0 {
+				fmt.Fprintln(w.out, "  At least one IP source is required.")
+				continue
+			}
+			return nil
+		}
+		if src.URL, err = w.require("Source URL", ""); err != nil {
+			return err
+		}
+		if src.Type, err = w.choose("Response type", "text", "text", "json", "trace"); err != nil {
+			return err
+		}
+		if src.Type == "json" {
+			if src.JSONPath, err = w.require("JSON path (e.g. data.ip)", ""); err != nil {
+				return err
+			}
+		}
+		a.IPSources = append(a.IPSources, src)
+	}
+}
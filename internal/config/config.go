@@ -2,6 +2,7 @@ package config
 
 import (
 	"io/ioutil"
+	"path/filepath"
 
 	"gopkg.in/yaml.v2"
 )
@@ -14,14 +15,17 @@ type IPSrc struct {
 }
 
 type CloudflareConfig struct {
-	APIToken string `yaml:"api_token"`
-	ZoneID   string `yaml:"zone_id"`
+	APIToken     string `yaml:"api_token"`
+	APITokenFile string `yaml:"api_token_file"` // 从文件读取 api_token
+	ZoneID       string `yaml:"zone_id"`
 }
 
 type AliyunConfig struct {
-	AccessKeyID     string `yaml:"access_key_id"`
-	AccessKeySecret string `yaml:"access_key_secret"`
-	Endpoint        string `yaml:"endpoint"`
+	AccessKeyID         string `yaml:"access_key_id"`
+	AccessKeyIDFile     string `yaml:"access_key_id_file"`
+	AccessKeySecret     string `yaml:"access_key_secret"`
+	AccessKeySecretFile string `yaml:"access_key_secret_file"`
+	Endpoint            string `yaml:"endpoint"`
 }
 
 type TencentCloudConfig struct {
@@ -29,28 +33,162 @@ type TencentCloudConfig struct {
 	SecretKey string `yaml:"secret_key"`
 }
 
+// RecordOptionsConfig 记录属性配置，未设置的项在创建时使用默认值、更新时保留现有值
+type RecordOptionsConfig struct {
+	TTL              int    `yaml:"ttl"`
+	Proxied          *bool  `yaml:"proxied"`  // 仅 Cloudflare
+	Comment          string `yaml:"comment"`  // Cloudflare 注释 / 阿里云备注
+	Line             string `yaml:"line"`     // 仅阿里云
+	Priority         int    `yaml:"priority"` // 仅阿里云
+	PreserveExisting bool   `yaml:"preserve_existing"`
+}
+
+// LogRotateConfig 日志文件轮转与保留设置，0 表示不启用对应规则
+type LogRotateConfig struct {
+	MaxSizeMB   int  `yaml:"max_size_mb"`
+	RotateHours int  `yaml:"rotate_hours"`
+	MaxBackups  int  `yaml:"max_backups"`
+	MaxAgeDays  int  `yaml:"max_age_days"`
+	Compress    bool `yaml:"compress"`
+}
+
+// LogSyslogConfig syslog 输出设置
+type LogSyslogConfig struct {
+	Network  string `yaml:"network"` // 空或 unix 为本机 syslog，udp/tcp 为远程
+	Address  string `yaml:"address"` // 远程 host:port，本机时可指定 socket 路径
+	Tag      string `yaml:"tag"`
+	Facility string `yaml:"facility"`
+}
+
+// HTTPConfig 内置 HTTP 服务设置，Listen 为空时不启动
+type HTTPConfig struct {
+	Listen    string `yaml:"listen"`     // host:port 或 unix:/path/to.sock
+	Metrics   bool   `yaml:"metrics"`    // 提供 Prometheus /metrics
+	Health    bool   `yaml:"health"`     // 提供 /healthz 和 /readyz
+	API       bool   `yaml:"api"`        // 提供 /api/v1 状态和控制接口，需要 Token
+	Token     string `yaml:"token"`      // API 的 Bearer Token
+	TokenFile string `yaml:"token_file"` // 从文件读取 token
+	Dashboard bool   `yaml:"dashboard"`  // 在 / 提供网页控制台
+	ReadOnly  bool   `yaml:"read_only"`  // 禁用 API 和控制台中的所有操作
+}
+
+// NotifierConfig 一个通知目标
+type NotifierConfig struct {
+	Type           string            `yaml:"type"`                // webhook, dingtalk, feishu, wecom, telegram, slack, discord, email
+	Name           string            `yaml:"name"`                // 日志中显示的名称，默认为 type
+	Events         []string          `yaml:"events"`              // 订阅的事件，默认全部；email 默认只订阅失败和恢复
+	TimeoutSeconds int               `yaml:"timeout_seconds"`     // 单次发送超时，默认 10
+	URL            string            `yaml:"url"`                 // webhook 地址；telegram 时为可选的 Bot API 地址
+	URLFile        string            `yaml:"url_file"`            // 从文件读取 url
+	Method         string            `yaml:"method"`              // webhook：默认 POST
+	Headers        map[string]string `yaml:"headers"`             // webhook：值支持 ${VAR} 和 enc:v1:
+	Body           string            `yaml:"body" expand:"false"` // webhook：text/template 模板，默认发送 JSON；不展开 ${VAR}
+	Secret         string            `yaml:"secret"`              // dingtalk、feishu：加签密钥，可选
+	SecretFile     string            `yaml:"secret_file"`         // 从文件读取 secret
+	BotToken       string            `yaml:"bot_token"`           // telegram
+	BotTokenFile   string            `yaml:"bot_token_file"`      // 从文件读取 bot_token
+	ChatID         string            `yaml:"chat_id"`             // telegram：用户、群组 ID 或 @频道名
+	SMTP           SMTPConfig        `yaml:"smtp"`                // email
+	Digest         string            `yaml:"digest"`              // email：每日摘要发送时间 HH:MM（本地时区），为空时不发送
+}
+
+// SMTPConfig 邮件通知的 SMTP 设置
+type SMTPConfig struct {
+	Host         string   `yaml:"host"`
+	Port         int      `yaml:"port"`     // 默认按 security 取 587、465 或 25
+	Security     string   `yaml:"security"` // starttls、tls 或 none，默认 465 端口为 tls，其它为 starttls
+	Username     string   `yaml:"username"` // 为空时不认证
+	Password     string   `yaml:"password"`
+	PasswordFile string   `yaml:"password_file"` // 从文件读取 password
+	From         string   `yaml:"from"`
+	To           []string `yaml:"to"`
+}
+
+// OnMissingConfig 连续多轮未获取到公网地址时对记录的处理策略
+type OnMissingConfig struct {
+	Action      string `yaml:"action"`       // keep, delete, set
+	AfterRounds int    `yaml:"after_rounds"` // 连续失败轮数，默认 3
+	Value       string `yaml:"value"`        // set 时写入的值
+	Type        string `yaml:"type"`         // set 时的记录类型，默认与原记录相同，可为 CNAME
+}
+
+// OwnershipConfig 记录归属标记配置，启用后只修改带有 OpenDDNS 标记的记录
+type OwnershipConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	OwnerID      string `yaml:"owner_id"`
+	AllowUnowned bool   `yaml:"allow_unowned"`
+}
+
 type Config struct {
-	Provider              string             `yaml:"provider"`
-	Domain                string             `yaml:"domain"`
-	Subdomain             string             `yaml:"subdomain"`
-	RecordType            string             `yaml:"record_type"` // A, AAAA, auto
-	IPSources             []IPSrc            `yaml:"ip_sources"`
-	UpdateIntervalMinutes int                `yaml:"update_interval_minutes"`
-	Cloudflare            CloudflareConfig   `yaml:"cloudflare"`
-	Aliyun                AliyunConfig       `yaml:"aliyun"`
-	TencentCloud          TencentCloudConfig `yaml:"tencentcloud"`
-	LogLevel              string             `yaml:"log_level"`
-	LogFile               string             `yaml:"log_file"`
+	Provider              string              `yaml:"provider"`
+	Domain                string              `yaml:"domain"`
+	Subdomain             string              `yaml:"subdomain"`
+	RecordType            string              `yaml:"record_type"` // A, AAAA, auto
+	RecordOptions         RecordOptionsConfig `yaml:"record_options"`
+	IPSources             []IPSrc             `yaml:"ip_sources"`
+	UpdateIntervalMinutes int                 `yaml:"update_interval_minutes"`
+	Cloudflare            CloudflareConfig    `yaml:"cloudflare"`
+	Aliyun                AliyunConfig        `yaml:"aliyun"`
+	TencentCloud          TencentCloudConfig  `yaml:"tencentcloud"`
+	Ownership             OwnershipConfig     `yaml:"ownership"`
+	OnMissing             OnMissingConfig     `yaml:"on_missing"`
+	LogLevel              string              `yaml:"log_level"`
+	LogFile               string              `yaml:"log_file"`
+	LogFormat             string              `yaml:"log_format"` // text 或 json
+	LogRotate             LogRotateConfig     `yaml:"log_rotate"`
+	LogOutput             []string            `yaml:"log_output"` // stdout、file、syslog、journald，可多选
+	LogSyslog             LogSyslogConfig     `yaml:"log_syslog"`
+	SecretKeyFile         string              `yaml:"secret_key_file"`        // 解密 enc:v1: 配置值的密钥文件
+	WatchConfig           bool                `yaml:"watch_config"`           // 配置文件变化时自动重新加载
+	ShutdownGraceSeconds  int                 `yaml:"shutdown_grace_seconds"` // 退出时等待进行中更新的秒数，默认 30
+	HTTP                  HTTPConfig          `yaml:"http"`
+	HistoryFile           string              `yaml:"history_file"` // 同步历史 JSONL 文件，相对路径基于配置文件所在目录
+	Notify                []NotifierConfig    `yaml:"notify"`
 }
 
+// LoadConfig 严格解析配置文件（未知字段报错），依次展开 ${VAR}、应用 OPENDDNS_* 环境变量覆盖、
+// 读取 *_file 凭证文件、解密 enc:v1: 加密值，最后校验所有字段，存在问题时返回包含全部问题的 *ValidationError
 func LoadConfig(path string) (*Config, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, err
+	var problems []string
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		typeErr, ok := err.(*yaml.TypeError)
+		if !ok {
+			return nil, err
+		}
+		problems = append(problems, typeErr.Errors...)
+	}
+	problems = append(problems, cfg.expandEnv()...)
+	problems = append(problems, cfg.applyEnvOverrides()...)
+	problems = append(problems, cfg.loadSecretFiles()...)
+	problems = append(problems, cfg.decryptSecrets(filepath.Dir(path))...)
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
 	}
 	return &cfg, nil
 }
+
+// Secrets 返回配置中的全部凭证值，用于在日志和错误输出中屏蔽。
+// 通知地址常在路径或参数中携带令牌，因此整个 URL 和所有请求头的值都视为凭证
+func (c *Config) Secrets() []string {
+	secrets := []string{
+		c.Cloudflare.APIToken,
+		c.Aliyun.AccessKeyID,
+		c.Aliyun.AccessKeySecret,
+		c.TencentCloud.SecretID,
+		c.TencentCloud.SecretKey,
+		c.HTTP.Token,
+	}
+	for _, n := range c.Notify {
+		secrets = append(secrets, n.URL, n.Secret, n.BotToken, n.SMTP.Password)
+		for _, v := range n.Headers {
+			secrets = append(secrets, v)
+		}
+	}
+	return secrets
+}
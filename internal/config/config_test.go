@@ -0,0 +1,38 @@
+package config
+
+import (
+	"OpenDDNS/internal/logger"
+	"strings"
+	"testing"
+)
+
+func TestSecretsRedacted(t *testing.T) {
+	defer logger.SetSecrets()
+	c := &Config{
+		Cloudflare:   CloudflareConfig{APIToken: "cf-token-123456"},
+		Aliyun:       AliyunConfig{AccessKeyID: "LTAI5tExample", AccessKeySecret: "aliyun-secret-value"},
+		TencentCloud: TencentCloudConfig{SecretID: "AKIDexample", SecretKey: "tencent-secret-key"},
+		HTTP:         HTTPConfig{Token: "dashboard-token-0123"},
+		Notify: []NotifierConfig{
+			{Type: "webhook", URL: "https://hooks.example.com/T000/B000/XXXX", Headers: map[string]string{"X-Api-Key": "webhook-header-key"}},
+			{Type: "dingtalk", URL: "https://oapi.dingtalk.com/robot/send?access_token=abc", Secret: "SECdingtalk-sign"},
+			{Type: "telegram", BotToken: "123456:telegram-bot-token", ChatID: "42"},
+			{Type: "email", SMTP: SMTPConfig{Username: "ddns", Password: "smtp-password"}},
+		},
+	}
+	logger.SetSecrets(c.Secrets()...)
+	values := []string{
+		"cf-token-123456", "LTAI5tExample", "aliyun-secret-value", "AKIDexample", "tencent-secret-key",
+		"dashboard-token-0123", "https://hooks.example.com/T000/B000/XXXX", "webhook-header-key",
+		"SECdingtalk-sign", "123456:telegram-bot-token", "smtp-password",
+	}
+	for _, v := range values {
+		if got := logger.Redact("error: " + v + " rejected"); strings.Contains(got, v) {
+			t.Errorf("Redact leaks %q: %q", v, got)
+		}
+	}
+	// 非凭证字段保持原样
+	if got := logger.Redact("chat 42 user ddns"); got != "chat 42 user ddns" {
+		t.Errorf("Redact changed non-secret text: %q", got)
+	}
+}
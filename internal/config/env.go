@@ -0,0 +1,270 @@
+package config
+
+import (
+	"OpenDDNS/internal/secret"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix 覆盖配置项的环境变量前缀，如 OPENDDNS_CLOUDFLARE_API_TOKEN
+const EnvPrefix = "OPENDDNS"
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// yamlName 返回字段的 yaml 键名，没有 yaml 标签时返回空
+func yamlName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// walkStrings 遍历结构体中所有字符串字段（含切片中的结构体和 map 的值），path 为 yaml 路径。
+// 标记为 expand:"false" 的字段（如 webhook 的 body 模板）原样保留，不做 ${VAR} 展开和解密
+func walkStrings(v reflect.Value, path string, fn func(field reflect.Value, path string)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walkStrings(v.Elem(), path, fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name := yamlName(v.Type().Field(i))
+			if name == "" || v.Type().Field(i).Tag.Get("expand") == "false" {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			walkStrings(v.Field(i), name, fn)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	case reflect.Map:
+		// map 的值不可寻址，复制后处理再写回
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			fn(value, fmt.Sprintf("%s.%v", path, key))
+			v.SetMapIndex(key, value)
+		}
+	case reflect.String:
+		fn(v, path)
+	}
+}
+
+// expandEnv 展开所有字符串字段中的 ${VAR} 与 ${VAR:-default}
+func (c *Config) expandEnv() []string {
+	var problems []string
+	walkStrings(reflect.ValueOf(c), "", func(field reflect.Value, path string) {
+		expanded := envPattern.ReplaceAllStringFunc(field.String(), func(ref string) string {
+			m := envPattern.FindStringSubmatch(ref)
+			if v, ok := os.LookupEnv(m[1]); ok {
+				return v
+			}
+			if m[2] != "" {
+				return m[3]
+			}
+			problems = append(problems, fmt.Sprintf("%s: environment variable %s is not set", path, m[1]))
+			return ""
+		})
+		field.SetString(expanded)
+	})
+	return problems
+}
+
+// applyEnvOverrides 用 OPENDDNS_<PATH> 环境变量覆盖标量配置项，
+// 如 OPENDDNS_DOMAIN、OPENDDNS_ALIYUN_ACCESS_KEY_SECRET；列表项（ip_sources）不支持覆盖
+func (c *Config) applyEnvOverrides() []string {
+	var problems []string
+	var walk func(v reflect.Value, env, path string)
+	walk = func(v reflect.Value, env, path string) {
+		for i := 0; i < v.NumField(); i++ {
+			name := yamlName(v.Type().Field(i))
+			if name == "" {
+				continue
+			}
+			fieldEnv := env + "_" + strings.ToUpper(name)
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			field := v.Field(i)
+			if field.Kind() == reflect.Struct {
+				walk(field, fieldEnv, fieldPath)
+				continue
+			}
+			value, ok := os.LookupEnv(fieldEnv)
+			if !ok {
+				continue
+			}
+			if err := setScalar(field, value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s (from %s): %v", fieldPath, fieldEnv, err))
+			}
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), EnvPrefix, "")
+	return problems
+}
+
+// setScalar 将字符串解析后写入标量字段
+func setScalar(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		// 字符串列表用逗号分隔，如 OPENDDNS_LOG_OUTPUT=stdout,journald
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("cannot be set from the environment")
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items).Convert(field.Type()))
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setScalar(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
+
+// readSecretFile 从 *_file 指定的文件读取凭证，value 已设置时两者冲突
+func readSecretFile(field string, value *string, path string) []string {
+	if path == "" {
+		return nil
+	}
+	if *value != "" {
+		return []string{fmt.Sprintf("%s and %s_file are mutually exclusive", field, field)}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("%s_file: %v", field, err)}
+	}
+	*value = strings.TrimRight(string(data), "\r\n")
+	return nil
+}
+
+// loadSecretFiles 读取 Docker/Kubernetes secret 等文件中的凭证
+func (c *Config) loadSecretFiles() []string {
+	var problems []string
+	problems = append(problems, readSecretFile("cloudflare.api_token", &c.Cloudflare.APIToken, c.Cloudflare.APITokenFile)...)
+	problems = append(problems, readSecretFile("aliyun.access_key_id", &c.Aliyun.AccessKeyID, c.Aliyun.AccessKeyIDFile)...)
+	problems = append(problems, readSecretFile("aliyun.access_key_secret", &c.Aliyun.AccessKeySecret, c.Aliyun.AccessKeySecretFile)...)
+	problems = append(problems, readSecretFile("http.token", &c.HTTP.Token, c.HTTP.TokenFile)...)
+	for i := range c.Notify {
+		n := &c.Notify[i]
+		field := fmt.Sprintf("notify[%d]", i)
+		problems = append(problems, readSecretFile(field+".url", &n.URL, n.URLFile)...)
+		problems = append(problems, readSecretFile(field+".secret", &n.Secret, n.SecretFile)...)
+		problems = append(problems, readSecretFile(field+".bot_token", &n.BotToken, n.BotTokenFile)...)
+		problems = append(problems, readSecretFile(field+".smtp.password", &n.SMTP.Password, n.SMTP.PasswordFile)...)
+	}
+	return problems
+}
+
+// PassphraseEnv 加密配置值的口令环境变量
+const PassphraseEnv = "OPENDDNS_SECRET_PASSPHRASE"
+
+// DefaultKeyFile 未配置 secret_key_file 时在配置文件同目录查找的密钥文件
+const DefaultKeyFile = "openddns.key"
+
+// keyFilePath 返回密钥文件路径，相对路径基于配置目录，未配置时为配置目录下的 openddns.key
+func keyFilePath(configDir, keyFile string) string {
+	if keyFile == "" {
+		keyFile = DefaultKeyFile
+	}
+	if filepath.IsAbs(keyFile) {
+		return keyFile
+	}
+	return filepath.Join(configDir, keyFile)
+}
+
+// SecretKeyFile 返回 configPath 对应的密钥文件路径，以及是否由 secret_key_file 显式配置。
+// 只读取 secret_key_file 一项，配置文件不存在或无法解析时按未配置处理
+func SecretKeyFile(configPath string) (string, bool) {
+	var c struct {
+		SecretKeyFile string `yaml:"secret_key_file"`
+	}
+	if data, err := os.ReadFile(configPath); err == nil {
+		yaml.Unmarshal(data, &c)
+	}
+	return keyFilePath(filepath.Dir(configPath), c.SecretKeyFile), c.SecretKeyFile != ""
+}
+
+// secretKey 依次从 secret_key_file、OPENDDNS_SECRET_PASSPHRASE、配置目录下的 openddns.key 获取密钥
+func (c *Config) secretKey(configDir string) (string, error) {
+	if c.SecretKeyFile != "" {
+		return secret.ReadKeyFile(keyFilePath(configDir, c.SecretKeyFile))
+	}
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	path := keyFilePath(configDir, "")
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no key available, set secret_key_file, %s or create %s", PassphraseEnv, path)
+	}
+	return secret.ReadKeyFile(path)
+}
+
+// decryptSecrets 透明解密所有 enc:v1: 开头的字符串字段
+func (c *Config) decryptSecrets(configDir string) []string {
+	var problems []string
+	var key string
+	var keyErr error
+	resolved := false
+	walkStrings(reflect.ValueOf(c), "", func(field reflect.Value, path string) {
+		if !secret.IsEncrypted(field.String()) {
+			return
+		}
+		if !resolved {
+			key, keyErr = c.secretKey(configDir)
+			resolved = true
+			if keyErr != nil {
+				problems = append(problems, fmt.Sprintf("%s: cannot decrypt: %v", path, keyErr))
+			}
+		}
+		if keyErr != nil {
+			return
+		}
+		plaintext, err := secret.Decrypt(field.String(), key)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: cannot decrypt: %v", path, err))
+			return
+		}
+		field.SetString(plaintext)
+	})
+	return problems
+}
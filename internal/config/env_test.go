@@ -0,0 +1,269 @@
+package config
+
+import (
+	"OpenDDNS/internal/secret"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile 在 dir 下写入文件并返回路径
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSecretKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name           string
+		config         string // 为空时不创建配置文件
+		want           string
+		wantConfigured bool
+	}{
+		{"missing config", "", filepath.Join(dir, DefaultKeyFile), false},
+		{"not configured", "provider: cloudflare\n", filepath.Join(dir, DefaultKeyFile), false},
+		{"relative", "secret_key_file: keys/k\n", filepath.Join(dir, "keys/k"), true},
+		{"absolute", "secret_key_file: /etc/openddns/k\n", "/etc/openddns/k", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config.yml")
+			os.Remove(path)
+			if tt.config != "" {
+				writeFile(t, dir, "config.yml", tt.config)
+			}
+			got, configured := SecretKeyFile(path)
+			if got != tt.want || configured != tt.wantConfigured {
+				t.Errorf("SecretKeyFile() = %q, %v; want %q, %v", got, configured, tt.want, tt.wantConfigured)
+			}
+		})
+	}
+}
+
+func TestDecryptSecretsWithDefaultKeyFile(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	dir := t.TempDir()
+	key, err := secret.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, DefaultKeyFile, key+"\n")
+	encrypted, err := secret.Encrypt("cf-token-123", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{Cloudflare: CloudflareConfig{APIToken: encrypted}}
+	if problems := c.decryptSecrets(dir); len(problems) > 0 {
+		t.Fatalf("decryptSecrets: %q", problems)
+	}
+	if c.Cloudflare.APIToken != "cf-token-123" {
+		t.Errorf("api_token = %q, want decrypted value", c.Cloudflare.APIToken)
+	}
+
+	other, _ := secret.GenerateKey()
+	encrypted, _ = secret.Encrypt("cf-token-123", other)
+	c = &Config{Cloudflare: CloudflareConfig{APIToken: encrypted}}
+	if problems := c.decryptSecrets(dir); len(problems) != 1 {
+		t.Errorf("decryptSecrets with wrong key: problems = %q, want one", problems)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("OD_TOKEN", "cf-token")
+	t.Setenv("OD_EMPTY", "")
+	c := &Config{
+		Domain:     "${OD_DOMAIN:-example.com}",
+		Subdomain:  "home-${OD_EMPTY:-x}",
+		Cloudflare: CloudflareConfig{APIToken: "${OD_TOKEN}"},
+		IPSources:  []IPSrc{{Name: "src", URL: "https://${OD_HOST:-ip.example.com}/"}},
+		Notify: []NotifierConfig{{
+			Type:    "webhook",
+			URL:     "https://hooks.example.com/${OD_TOKEN}",
+			Headers: map[string]string{"Authorization": "Bearer ${OD_TOKEN}"},
+			Body:    `{"cmd": "echo ${OD_TOKEN}", "ip": {{json .NewIP}}}`,
+		}},
+	}
+	if problems := c.expandEnv(); len(problems) > 0 {
+		t.Fatalf("expandEnv: %q", problems)
+	}
+	for _, tt := range []struct{ name, got, want string }{
+		{"default", c.Domain, "example.com"},
+		{"set but empty", c.Subdomain, "home-"},
+		{"plain", c.Cloudflare.APIToken, "cf-token"},
+		{"slice", c.IPSources[0].URL, "https://ip.example.com/"},
+		{"notify url", c.Notify[0].URL, "https://hooks.example.com/cf-token"},
+		{"map value", c.Notify[0].Headers["Authorization"], "Bearer cf-token"},
+		{"template body untouched", c.Notify[0].Body, `{"cmd": "echo ${OD_TOKEN}", "ip": {{json .NewIP}}}`},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	c = &Config{Domain: "${OD_MISSING}.example.com", Notify: []NotifierConfig{{Body: "${OD_MISSING}"}}}
+	problems := c.expandEnv()
+	if len(problems) != 1 || problems[0] != "domain: environment variable OD_MISSING is not set" {
+		t.Errorf("unset variable: problems = %q", problems)
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	t.Setenv("OPENDDNS_DOMAIN", "example.org")
+	t.Setenv("OPENDDNS_CLOUDFLARE_API_TOKEN", "from-env")
+	t.Setenv("OPENDDNS_UPDATE_INTERVAL_MINUTES", "10")
+	t.Setenv("OPENDDNS_RECORD_OPTIONS_TTL", "300")
+	t.Setenv("OPENDDNS_RECORD_OPTIONS_PROXIED", "true")
+	t.Setenv("OPENDDNS_LOG_OUTPUT", "stdout, journald,")
+	c := &Config{Domain: "example.com", Cloudflare: CloudflareConfig{APIToken: "from-file"}, UpdateIntervalMinutes: 5}
+	if problems := c.applyEnvOverrides(); len(problems) > 0 {
+		t.Fatalf("applyEnvOverrides: %q", problems)
+	}
+	if c.Domain != "example.org" || c.Cloudflare.APIToken != "from-env" || c.UpdateIntervalMinutes != 10 || c.RecordOptions.TTL != 300 {
+		t.Errorf("scalars not overridden: %+v", c)
+	}
+	if c.RecordOptions.Proxied == nil || !*c.RecordOptions.Proxied {
+		t.Errorf("pointer bool not overridden: %v", c.RecordOptions.Proxied)
+	}
+	if len(c.LogOutput) != 2 || c.LogOutput[0] != "stdout" || c.LogOutput[1] != "journald" {
+		t.Errorf("log_output = %q", c.LogOutput)
+	}
+
+	t.Setenv("OPENDDNS_UPDATE_INTERVAL_MINUTES", "ten")
+	t.Setenv("OPENDDNS_HTTP_API", "maybe")
+	problems := (&Config{}).applyEnvOverrides()
+	want := []string{
+		`update_interval_minutes (from OPENDDNS_UPDATE_INTERVAL_MINUTES): invalid integer "ten"`,
+		`http.api (from OPENDDNS_HTTP_API): invalid boolean "maybe"`,
+	}
+	for _, w := range want {
+		found := false
+		for _, p := range problems {
+			found = found || p == w
+		}
+		if !found {
+			t.Errorf("problems %q missing %q", problems, w)
+		}
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := writeFile(t, dir, "token", "cf-token\r\n")
+	tests := []struct {
+		name  string
+		cf    CloudflareConfig
+		want  string
+		error string
+	}{
+		{"file trims trailing newline", CloudflareConfig{APITokenFile: tokenFile}, "cf-token", ""},
+		{"value only", CloudflareConfig{APIToken: "inline"}, "inline", ""},
+		{"value and file conflict", CloudflareConfig{APIToken: "inline", APITokenFile: tokenFile}, "inline",
+			"cloudflare.api_token and cloudflare.api_token_file are mutually exclusive"},
+		{"missing file", CloudflareConfig{APITokenFile: filepath.Join(dir, "missing")}, "", "cloudflare.api_token_file: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Cloudflare: tt.cf}
+			problems := c.loadSecretFiles()
+			if c.Cloudflare.APIToken != tt.want {
+				t.Errorf("api_token = %q, want %q", c.Cloudflare.APIToken, tt.want)
+			}
+			switch {
+			case tt.error == "" && len(problems) > 0:
+				t.Errorf("unexpected problems: %q", problems)
+			case tt.error != "" && (len(problems) != 1 || !strings.HasPrefix(problems[0], tt.error)):
+				t.Errorf("problems = %q, want %q", problems, tt.error)
+			}
+		})
+	}
+}
+
+// TestLoadConfigPrecedence 校验完整加载流程中 ${VAR}、OPENDDNS_* 和 *_file 的先后顺序
+func TestLoadConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "token", "from-file\n")
+	base := `provider: cloudflare
+domain: "${OD_DOMAIN:-example.com}"
+subdomain: home
+update_interval_minutes: 5
+ip_sources:
+  - name: ipify
+    url: https://api.ipify.org
+    type: text
+`
+	load := func(t *testing.T, extra string) (*Config, error) {
+		t.Helper()
+		return LoadConfig(writeFile(t, dir, "config.yml", base+extra))
+	}
+
+	t.Run("file", func(t *testing.T) {
+		c, err := load(t, "cloudflare:\n  api_token_file: "+filepath.Join(dir, "token")+"\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Cloudflare.APIToken != "from-file" || c.Domain != "example.com" {
+			t.Errorf("got token %q domain %q", c.Cloudflare.APIToken, c.Domain)
+		}
+	})
+	t.Run("env override beats yaml", func(t *testing.T) {
+		t.Setenv("OD_DOMAIN", "example.net")
+		t.Setenv("OPENDDNS_CLOUDFLARE_API_TOKEN", "from-override")
+		c, err := load(t, "cloudflare:\n  api_token: \"${OD_TOKEN:-from-yaml}\"\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Cloudflare.APIToken != "from-override" || c.Domain != "example.net" {
+			t.Errorf("got token %q domain %q", c.Cloudflare.APIToken, c.Domain)
+		}
+	})
+	t.Run("env override conflicts with file", func(t *testing.T) {
+		t.Setenv("OPENDDNS_CLOUDFLARE_API_TOKEN", "from-override")
+		_, err := load(t, "cloudflare:\n  api_token_file: "+filepath.Join(dir, "token")+"\n")
+		if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+			t.Errorf("err = %v, want mutually exclusive", err)
+		}
+	})
+	t.Run("env override of file path", func(t *testing.T) {
+		t.Setenv("OPENDDNS_CLOUDFLARE_API_TOKEN_FILE", filepath.Join(dir, "token"))
+		c, err := load(t, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Cloudflare.APIToken != "from-file" {
+			t.Errorf("got token %q", c.Cloudflare.APIToken)
+		}
+	})
+}
+
+func TestLoadNotifySecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	c := &Config{Notify: []NotifierConfig{
+		{Type: "dingtalk", URLFile: writeFile(t, dir, "url", "https://oapi.dingtalk.com/robot/send?access_token=abc\n"), SecretFile: writeFile(t, dir, "secret", "SECabc\n")},
+		{Type: "telegram", BotTokenFile: writeFile(t, dir, "bot", "123:abc\n"), ChatID: "42"},
+		{Type: "email", SMTP: SMTPConfig{PasswordFile: writeFile(t, dir, "smtp", "hunter2\n")}},
+		{Type: "feishu", URL: "https://open.feishu.cn/hook", URLFile: filepath.Join(dir, "url")},
+	}}
+	problems := c.loadSecretFiles()
+	if want := "notify[3].url and notify[3].url_file are mutually exclusive"; len(problems) != 1 || problems[0] != want {
+		t.Errorf("problems = %q, want %q", problems, want)
+	}
+	n := c.Notify
+	if n[0].URL != "https://oapi.dingtalk.com/robot/send?access_token=abc" || n[0].Secret != "SECabc" {
+		t.Errorf("dingtalk = %q, %q", n[0].URL, n[0].Secret)
+	}
+	if n[1].BotToken != "123:abc" {
+		t.Errorf("bot_token = %q", n[1].BotToken)
+	}
+	if n[2].SMTP.Password != "hunter2" {
+		t.Errorf("smtp.password = %q", n[2].SMTP.Password)
+	}
+}
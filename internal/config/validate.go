@@ -0,0 +1,316 @@
+package config
+
+import (
+	"OpenDDNS/internal/logger"
+	"OpenDDNS/internal/notify"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ValidationError 汇总配置中的所有问题
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d problem(s) in config:\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+var domainPattern = regexp.MustCompile(`^(?i)[a-z0-9_]([a-z0-9_-]*[a-z0-9_])?(\.[a-z0-9_]([a-z0-9_-]*[a-z0-9_])?)*$`)
+
+// isPlaceholder 判断是否为默认模板中的占位凭证
+func isPlaceholder(v string) bool {
+	return strings.HasPrefix(v, "YOUR_")
+}
+
+// validate 对各字段做语义校验，返回全部问题
+func (c *Config) validate() []string {
+	var problems []string
+	add := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+	// oneOf 校验可选值，空值视为使用默认值
+	oneOf := func(field, value string, allowed ...string) {
+		if value == "" {
+			return
+		}
+		for _, v := range allowed {
+			if strings.EqualFold(value, v) {
+				return
+			}
+		}
+		add("%s: invalid value %q, expected one of: %s", field, value, strings.Join(allowed, ", "))
+	}
+
+	switch c.Provider {
+	case "":
+		add("provider: required, expected one of: cloudflare, aliyun")
+	case "cloudflare":
+		if c.Cloudflare.APIToken == "" || isPlaceholder(c.Cloudflare.APIToken) {
+			add("cloudflare.api_token: required (or api_token_file / OPENDDNS_CLOUDFLARE_API_TOKEN), replace the placeholder with a real API token")
+		}
+	case "aliyun":
+		if c.Aliyun.AccessKeyID == "" || isPlaceholder(c.Aliyun.AccessKeyID) {
+			add("aliyun.access_key_id: required (or access_key_id_file / OPENDDNS_ALIYUN_ACCESS_KEY_ID), replace the placeholder with a real AccessKey ID")
+		}
+		if c.Aliyun.AccessKeySecret == "" || isPlaceholder(c.Aliyun.AccessKeySecret) {
+			add("aliyun.access_key_secret: required (or access_key_secret_file / OPENDDNS_ALIYUN_ACCESS_KEY_SECRET), replace the placeholder with a real AccessKey Secret")
+		}
+	default:
+		add("provider: unsupported provider %q, expected one of: cloudflare, aliyun", c.Provider)
+	}
+
+	switch {
+	case c.Domain == "":
+		add("domain: required, e.g. \"example.com\"")
+	case strings.Contains(c.Domain, "://") || !domainPattern.MatchString(c.Domain) || !strings.Contains(c.Domain, "."):
+		add("domain: %q is not a valid domain name, e.g. \"example.com\"", c.Domain)
+	}
+	switch {
+	case c.Subdomain == "":
+		add("subdomain: required, e.g. \"www\"")
+	case !domainPattern.MatchString(c.Subdomain):
+		add("subdomain: %q is not a valid host label", c.Subdomain)
+	}
+
+	oneOf("record_type", c.RecordType, "auto", "A", "AAAA")
+	oneOf("log_level", c.LogLevel, "debug", "info", "warn", "error")
+	oneOf("log_format", c.LogFormat, "text", "json")
+	for i, out := range c.LogOutput {
+		oneOf(fmt.Sprintf("log_output[%d]", i), out, "stdout", "file", "syslog", "journald")
+		if strings.EqualFold(out, "file") && c.LogFile == "" {
+			add("log_output[%d]: \"file\" requires log_file", i)
+		}
+	}
+	sys := c.LogSyslog
+	oneOf("log_syslog.network", sys.Network, "unix", "udp", "tcp")
+	if (strings.EqualFold(sys.Network, "udp") || strings.EqualFold(sys.Network, "tcp")) && sys.Address == "" {
+		add("log_syslog.address: required when network is %q, e.g. \"192.168.1.10:514\"", sys.Network)
+	}
+	if !logger.ValidFacility(sys.Facility) {
+		add("log_syslog.facility: invalid value %q, expected e.g. daemon, user or local0..local7", sys.Facility)
+	}
+	if listen := c.HTTP.Listen; listen != "" {
+		if strings.HasPrefix(listen, "unix:") {
+			if strings.TrimPrefix(listen, "unix:") == "" {
+				add("http.listen: unix socket path is empty, e.g. \"unix:/run/openddns.sock\"")
+			}
+		} else if _, _, err := net.SplitHostPort(listen); err != nil {
+			add("http.listen: %q is not host:port or unix:/path, e.g. \"127.0.0.1:9876\"", listen)
+		}
+	}
+	if c.HTTP.Metrics && c.HTTP.Listen == "" {
+		add("http.metrics: requires http.listen")
+	}
+	if c.HTTP.Health && c.HTTP.Listen == "" {
+		add("http.health: requires http.listen")
+	}
+	if c.HTTP.API {
+		if c.HTTP.Listen == "" {
+			add("http.api: requires http.listen")
+		} else if !strings.HasPrefix(c.HTTP.Listen, "unix:") && !isLoopback(c.HTTP.Listen) {
+			add("http.api: http.listen must be a unix socket or a loopback address such as 127.0.0.1, got %q", c.HTTP.Listen)
+		}
+		if c.HTTP.Token == "" {
+			add("http.token: required when http.api is enabled")
+		}
+	}
+	if c.HTTP.Dashboard {
+		if c.HTTP.Listen == "" {
+			add("http.dashboard: requires http.listen")
+		}
+		// 控制台数据接口与 /api/v1 相同，监听在本机以外时即使只读也必须设置 token
+		if c.HTTP.Listen != "" && c.HTTP.Token == "" && !strings.HasPrefix(c.HTTP.Listen, "unix:") && !isLoopback(c.HTTP.Listen) {
+			add("http.token: required when the dashboard listens on a non-loopback address, got %q", c.HTTP.Listen)
+		} else if c.HTTP.Token == "" && !c.HTTP.ReadOnly {
+			add("http.token: required when the dashboard allows actions, or set http.read_only: true")
+		}
+	}
+	if c.HTTP.Token != "" && len(c.HTTP.Token) < 16 {
+		add("http.token: must be at least 16 characters")
+	}
+
+	for i, n := range c.Notify {
+		field := fmt.Sprintf("notify[%d]", i)
+		checkURL := func() {
+			if u, err := url.Parse(n.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("%s.url: must be an http(s) URL", field)
+			}
+		}
+		switch strings.ToLower(n.Type) {
+		case "":
+			add("%s.type: required, expected one of: %s", field, notifierTypes)
+		case "webhook":
+			checkURL()
+			if n.Body != "" {
+				if _, err := notify.ParseTemplate("body", n.Body); err != nil {
+					add("%s.body: %v", field, err)
+				}
+			}
+		case "dingtalk", "feishu", "wecom", "slack", "discord":
+			checkURL()
+		case "telegram":
+			if n.URL != "" {
+				checkURL()
+			}
+			if n.BotToken == "" {
+				add("%s.bot_token: required for telegram (or bot_token_file)", field)
+			}
+			if n.ChatID == "" {
+				add("%s.chat_id: required for telegram", field)
+			}
+		case "email":
+			smtp := n.SMTP
+			if smtp.Host == "" {
+				add("%s.smtp.host: required for email", field)
+			}
+			if smtp.Port < 0 || smtp.Port > 65535 {
+				add("%s.smtp.port: must be between 1 and 65535 or 0 for the default, got %d", field, smtp.Port)
+			}
+			oneOf(field+".smtp.security", smtp.Security, "starttls", "tls", "none")
+			if smtp.Username != "" && smtp.Password == "" {
+				add("%s.smtp.password: required when username is set", field)
+			}
+			// 不加密时只允许向本机中继认证，否则密码会明文发送
+			if smtp.Username != "" && strings.EqualFold(smtp.Security, "none") && !isLoopback(net.JoinHostPort(smtp.Host, "0")) {
+				add("%s.smtp.security: none cannot be used with username unless host is localhost, use starttls or tls", field)
+			}
+			if _, err := mail.ParseAddress(smtp.From); err != nil {
+				add("%s.smtp.from: invalid address %q", field, smtp.From)
+			}
+			if len(smtp.To) == 0 {
+				add("%s.smtp.to: at least one recipient is required", field)
+			}
+			for _, to := range smtp.To {
+				if _, err := mail.ParseAddress(to); err != nil {
+					add("%s.smtp.to: invalid address %q", field, to)
+				}
+			}
+		default:
+			add("%s.type: invalid value %q, expected one of: %s", field, n.Type, notifierTypes)
+		}
+		for _, e := range n.Events {
+			if !notify.ValidEvent(e) {
+				add("%s.events: invalid event %q, expected any of: %s", field, e, strings.Join(notify.Events, ", "))
+			}
+		}
+		if n.TimeoutSeconds < 0 {
+			add("%s.timeout_seconds: must not be negative", field)
+		}
+		if n.Digest != "" {
+			if !strings.EqualFold(n.Type, "email") {
+				add("%s.digest: only supported for email", field)
+			} else if _, err := notify.ParseDigestTime(n.Digest); err != nil {
+				add("%s.digest: %v", field, err)
+			}
+		}
+	}
+
+	rotate := c.LogRotate
+	for _, f := range []struct {
+		field string
+		value int
+	}{
+		{"log_rotate.max_size_mb", rotate.MaxSizeMB},
+		{"log_rotate.rotate_hours", rotate.RotateHours},
+		{"log_rotate.max_backups", rotate.MaxBackups},
+		{"log_rotate.max_age_days", rotate.MaxAgeDays},
+	} {
+		if f.value < 0 {
+			add("%s: must not be negative, got %d", f.field, f.value)
+		}
+	}
+
+	if c.UpdateIntervalMinutes < 1 {
+		add("update_interval_minutes: must be at least 1, got %d", c.UpdateIntervalMinutes)
+	}
+
+	if c.ShutdownGraceSeconds < 0 {
+		add("shutdown_grace_seconds: must not be negative, got %d", c.ShutdownGraceSeconds)
+	}
+
+	if len(c.IPSources) == 0 {
+		add("ip_sources: at least one IP source is required")
+	}
+	names := make(map[string]int)
+	for i, src := range c.IPSources {
+		field := fmt.Sprintf("ip_sources[%d]", i)
+		if src.Name == "" {
+			add("%s.name: required", field)
+		} else if j, ok := names[src.Name]; ok {
+			add("%s.name: %q duplicates ip_sources[%d]", field, src.Name, j)
+		} else {
+			names[src.Name] = i
+		}
+		if u, err := url.Parse(src.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("%s.url: %q is not a valid http(s) URL", field, src.URL)
+		}
+		switch src.Type {
+		case "json":
+			if src.JSONPath == "" {
+				add("%s.json_path: required when type is \"json\", e.g. \"data.addr\"", field)
+			}
+		case "trace", "text":
+		default:
+			add("%s.type: invalid value %q, expected one of: json, trace, text", field, src.Type)
+		}
+	}
+
+	opts := c.RecordOptions
+	if opts.TTL < 0 || opts.TTL > 86400 {
+		add("record_options.ttl: must be between 0 and 86400, got %d", opts.TTL)
+	}
+	if opts.Priority < 0 {
+		add("record_options.priority: must not be negative, got %d", opts.Priority)
+	}
+
+	oneOf("on_missing.action", c.OnMissing.Action, "keep", "delete", "set")
+	if c.OnMissing.AfterRounds < 0 {
+		add("on_missing.after_rounds: must not be negative, got %d", c.OnMissing.AfterRounds)
+	}
+	oneOf("on_missing.type", c.OnMissing.Type, "A", "AAAA", "CNAME")
+	if strings.EqualFold(c.OnMissing.Action, "set") {
+		// 占位值按写入的记录类型校验，type 默认与原记录相同
+		value := c.OnMissing.Value
+		fallbackType := strings.ToUpper(c.OnMissing.Type)
+		if fallbackType == "" && !strings.EqualFold(c.RecordType, "auto") {
+			fallbackType = strings.ToUpper(c.RecordType) // 为空时同样按 auto 处理
+		}
+		addr, ipErr := netip.ParseAddr(value)
+		switch {
+		case value == "":
+			add("on_missing.value: required when action is \"set\"")
+		case fallbackType == "":
+			add("on_missing.type: required when action is \"set\" and record_type is auto, e.g. \"A\" or \"CNAME\"")
+		case fallbackType == "A" && (ipErr != nil || !addr.Is4()):
+			add("on_missing.value: %q is not an IPv4 address, required for type A", value)
+		case fallbackType == "AAAA" && (ipErr != nil || !addr.Is6() || addr.Is4In6()):
+			add("on_missing.value: %q is not an IPv6 address, required for type AAAA", value)
+		case fallbackType == "CNAME" && (ipErr == nil || !domainPattern.MatchString(strings.TrimSuffix(value, ".")) || !strings.Contains(value, ".")):
+			add("on_missing.value: %q is not a host name, required for type CNAME, e.g. \"offline.example.com\"", value)
+		}
+	}
+
+	return problems
+}
+
+// notifierTypes 支持的通知方式，用于错误提示
+const notifierTypes = "webhook, dingtalk, feishu, wecom, telegram, slack, discord, email"
+
+// isLoopback 判断 host:port 是否只监听本机回环地址
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// validConfig 返回一份能通过校验的最小配置
+func validConfig() *Config {
+	return &Config{
+		Provider:              "cloudflare",
+		Domain:                "example.com",
+		Subdomain:             "home",
+		Cloudflare:            CloudflareConfig{APIToken: "token"},
+		UpdateIntervalMinutes: 5,
+		IPSources:             []IPSrc{{Name: "ipify", URL: "https://api.ipify.org", Type: "text"}},
+	}
+}
+
+// checkValidate 修改 validConfig 后校验，want 为空时期望没有问题，否则期望某条问题包含 want
+func checkValidate(t *testing.T, mutate func(*Config), want string) {
+	t.Helper()
+	c := validConfig()
+	mutate(c)
+	problems := c.validate()
+	if want == "" {
+		if len(problems) > 0 {
+			t.Errorf("unexpected problems: %q", problems)
+		}
+		return
+	}
+	for _, p := range problems {
+		if strings.Contains(p, want) {
+			return
+		}
+	}
+	t.Errorf("problems %q do not contain %q", problems, want)
+}
+
+func TestValidateBase(t *testing.T) {
+	checkValidate(t, func(*Config) {}, "")
+}
+
+func TestValidateEmail(t *testing.T) {
+	email := func(smtp SMTPConfig) func(*Config) {
+		return func(c *Config) {
+			smtp.From = "ddns@example.com"
+			smtp.To = []string{"ops@example.com"}
+			c.Notify = []NotifierConfig{{Type: "email", SMTP: smtp}}
+		}
+	}
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{"starttls with auth", email(SMTPConfig{Host: "smtp.example.com", Username: "u", Password: "p"}), ""},
+		{"tls with auth", email(SMTPConfig{Host: "smtp.example.com", Security: "tls", Username: "u", Password: "p"}), ""},
+		{"none without auth", email(SMTPConfig{Host: "relay.lan", Security: "none"}), ""},
+		{"none with auth on localhost", email(SMTPConfig{Host: "localhost", Security: "none", Username: "u", Password: "p"}), ""},
+		{"none with auth on loopback ip", email(SMTPConfig{Host: "127.0.0.1", Security: "none", Username: "u", Password: "p"}), ""},
+		{"none with auth on remote host", email(SMTPConfig{Host: "smtp.example.com", Security: "none", Username: "u", Password: "p"}),
+			"notify[0].smtp.security: none cannot be used with username"},
+		{"none uppercase with auth on remote host", email(SMTPConfig{Host: "smtp.example.com", Security: "NONE", Username: "u", Password: "p"}),
+			"notify[0].smtp.security: none cannot be used with username"},
+		{"username without password", email(SMTPConfig{Host: "smtp.example.com", Username: "u"}), "notify[0].smtp.password: required"},
+		{"missing host", email(SMTPConfig{}), "notify[0].smtp.host: required"},
+		{"invalid security", email(SMTPConfig{Host: "smtp.example.com", Security: "ssl"}), "notify[0].smtp.security: invalid value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, tt.mutate, tt.want)
+		})
+	}
+}
+
+func TestValidateOnMissing(t *testing.T) {
+	set := func(recordType, fallbackType, value string) func(*Config) {
+		return func(c *Config) {
+			c.RecordType = recordType
+			c.OnMissing = OnMissingConfig{Action: "set", Type: fallbackType, Value: value}
+		}
+	}
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{"keep", func(c *Config) { c.OnMissing.Action = "keep" }, ""},
+		{"delete", func(c *Config) { c.OnMissing.Action = "delete" }, ""},
+		{"invalid action", func(c *Config) { c.OnMissing.Action = "drop" }, "on_missing.action: invalid value"},
+		{"negative rounds", func(c *Config) { c.OnMissing.AfterRounds = -1 }, "on_missing.after_rounds: must not be negative"},
+		{"set without value", set("A", "", ""), "on_missing.value: required"},
+		{"A from record type", set("A", "", "192.0.2.1"), ""},
+		{"A rejects IPv6", set("A", "", "2001:db8::1"), "not an IPv4 address"},
+		{"A rejects host name", set("A", "", "offline.example.com"), "not an IPv4 address"},
+		{"AAAA from record type", set("AAAA", "", "2001:db8::1"), ""},
+		{"AAAA rejects IPv4", set("AAAA", "", "192.0.2.1"), "not an IPv6 address"},
+		{"AAAA rejects mapped IPv4", set("AAAA", "", "::ffff:192.0.2.1"), "not an IPv6 address"},
+		{"explicit type overrides record type", set("A", "AAAA", "2001:db8::1"), ""},
+		{"lowercase type", set("AAAA", "a", "192.0.2.1"), ""},
+		{"CNAME host", set("A", "CNAME", "offline.example.com"), ""},
+		{"CNAME trailing dot", set("A", "CNAME", "offline.example.com."), ""},
+		{"CNAME rejects IP", set("A", "CNAME", "192.0.2.1"), "not a host name"},
+		{"CNAME rejects URL", set("A", "CNAME", "https://offline.example.com"), "not a host name"},
+		{"CNAME rejects single label", set("A", "CNAME", "offline"), "not a host name"},
+		{"auto requires type", set("auto", "", "192.0.2.1"), "on_missing.type: required"},
+		{"empty record type requires type", set("", "", "192.0.2.1"), "on_missing.type: required"},
+		{"auto with type", set("auto", "A", "192.0.2.1"), ""},
+		{"invalid type", set("A", "MX", "mail.example.com"), "on_missing.type: invalid value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, tt.mutate, tt.want)
+		})
+	}
+}
+
+func TestValidateHTTP(t *testing.T) {
+	const token = "0123456789abcdef"
+	http := func(h HTTPConfig) func(*Config) {
+		return func(c *Config) { c.HTTP = h }
+	}
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{"metrics on any address", http(HTTPConfig{Listen: ":9876", Metrics: true}), ""},
+		{"metrics without listen", http(HTTPConfig{Metrics: true}), "http.metrics: requires http.listen"},
+		{"invalid listen", http(HTTPConfig{Listen: "9876", Metrics: true}), "is not host:port or unix:/path"},
+		{"empty unix path", http(HTTPConfig{Listen: "unix:", Metrics: true}), "unix socket path is empty"},
+		{"api on loopback", http(HTTPConfig{Listen: "127.0.0.1:9876", API: true, Token: token}), ""},
+		{"api on unix socket", http(HTTPConfig{Listen: "unix:/run/openddns.sock", API: true, Token: token}), ""},
+		{"api on all interfaces", http(HTTPConfig{Listen: ":9876", API: true, Token: token}), "http.api: http.listen must be a unix socket or a loopback address"},
+		{"api without token", http(HTTPConfig{Listen: "127.0.0.1:9876", API: true}), "http.token: required when http.api is enabled"},
+		{"short token", http(HTTPConfig{Listen: "127.0.0.1:9876", API: true, Token: "short"}), "http.token: must be at least 16 characters"},
+		{"read-only dashboard on loopback", http(HTTPConfig{Listen: "127.0.0.1:9876", Dashboard: true, ReadOnly: true}), ""},
+		{"read-only dashboard on localhost", http(HTTPConfig{Listen: "localhost:9876", Dashboard: true, ReadOnly: true}), ""},
+		{"read-only dashboard on unix socket", http(HTTPConfig{Listen: "unix:/run/openddns.sock", Dashboard: true, ReadOnly: true}), ""},
+		{"read-only dashboard on all interfaces", http(HTTPConfig{Listen: ":9876", Dashboard: true, ReadOnly: true}),
+			"http.token: required when the dashboard listens on a non-loopback address"},
+		{"read-only dashboard on LAN address", http(HTTPConfig{Listen: "192.168.1.2:9876", Dashboard: true, ReadOnly: true}),
+			"http.token: required when the dashboard listens on a non-loopback address"},
+		{"dashboard on all interfaces with token", http(HTTPConfig{Listen: "0.0.0.0:9876", Dashboard: true, Token: token}), ""},
+		{"writable dashboard without token", http(HTTPConfig{Listen: "127.0.0.1:9876", Dashboard: true}),
+			"http.token: required when the dashboard allows actions"},
+		{"dashboard without listen", http(HTTPConfig{Dashboard: true, Token: token}), "http.dashboard: requires http.listen"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, tt.mutate, tt.want)
+		})
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{"missing provider", func(c *Config) { c.Provider = "" }, "provider: required"},
+		{"unknown provider", func(c *Config) { c.Provider = "route53" }, "provider: unsupported provider"},
+		{"placeholder token", func(c *Config) { c.Cloudflare.APIToken = "YOUR_CLOUDFLARE_API_TOKEN" }, "cloudflare.api_token: required"},
+		{"aliyun credentials", func(c *Config) { c.Provider = "aliyun" }, "aliyun.access_key_id: required"},
+		{"domain with scheme", func(c *Config) { c.Domain = "https://example.com" }, "is not a valid domain name"},
+		{"domain without dot", func(c *Config) { c.Domain = "localhost" }, "is not a valid domain name"},
+		{"invalid subdomain", func(c *Config) { c.Subdomain = "home router" }, "is not a valid host label"},
+		{"record type", func(c *Config) { c.RecordType = "MX" }, "record_type: invalid value"},
+		{"record type case insensitive", func(c *Config) { c.RecordType = "aaaa" }, ""},
+		{"interval", func(c *Config) { c.UpdateIntervalMinutes = 0 }, "update_interval_minutes: must be at least 1"},
+		{"no ip sources", func(c *Config) { c.IPSources = nil }, "ip_sources: at least one IP source is required"},
+		{"duplicate ip source", func(c *Config) { c.IPSources = append(c.IPSources, c.IPSources[0]) }, "duplicates ip_sources[0]"},
+		{"json source without path", func(c *Config) { c.IPSources[0].Type = "json" }, "json_path: required"},
+		{"ttl", func(c *Config) { c.RecordOptions.TTL = 90000 }, "record_options.ttl: must be between 0 and 86400"},
+		{"log file output", func(c *Config) { c.LogOutput = []string{"file"} }, "\"file\" requires log_file"},
+		{"syslog udp without address", func(c *Config) { c.LogSyslog.Network = "udp" }, "log_syslog.address: required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, tt.mutate, tt.want)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package dashboard
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+)
+
+// APIPrefix 控制台数据接口的路径前缀，与 /api/v1 的接口相同
+const APIPrefix = "/ui/v1"
+
+//go:embed static
+var files embed.FS
+
+// Handler 返回控制台页面和静态资源的 handler，readOnly 时不显示操作按钮，
+// tokenRequired 时页面会在接口返回 401 后提示输入令牌
+func Handler(readOnly, tokenRequired bool) (http.Handler, error) {
+	index, err := template.ParseFS(files, "static/index.html")
+	if err != nil {
+		return nil, err
+	}
+	data := struct {
+		ReadOnly      bool
+		TokenRequired bool
+	}{readOnly, tokenRequired}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /static/", http.FileServerFS(files))
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Frame-Options", "DENY")
+		index.Execute(w, data)
+	})
+	return mux, nil
+}
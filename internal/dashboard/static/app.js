@@ -0,0 +1,201 @@
+"use strict";
+
+// 数据接口相对于页面路径，便于放在反向代理的子路径下
+const API = "ui/v1";
+const TOKEN_KEY = "openddns-token";
+const REFRESH_MS = 10000;
+
+const readOnly = document.body.dataset.readOnly === "true";
+const tokenRequired = document.body.dataset.tokenRequired === "true";
+
+const EVENT_CLASS = {
+  ip_changed: "warn",
+  update_succeeded: "ok",
+  update_failed: "bad",
+  detection_failed: "bad",
+  recovered: "ok",
+};
+
+function $(id) {
+  return document.getElementById(id);
+}
+
+// el 创建元素，文本一律通过 textContent 写入
+function el(tag, text, className) {
+  const node = document.createElement(tag);
+  if (text !== undefined && text !== null) node.textContent = text;
+  if (className) node.className = className;
+  return node;
+}
+
+function badge(text, className) {
+  return el("span", text, "badge " + className);
+}
+
+function formatTime(value) {
+  if (!value) return "-";
+  return new Date(value).toLocaleString();
+}
+
+function relative(value) {
+  if (!value) return "-";
+  const seconds = Math.round((new Date(value) - Date.now()) / 1000);
+  const abs = Math.abs(seconds);
+  const text = abs < 60 ? abs + "s" : abs < 3600 ? Math.round(abs / 60) + "m" : Math.round(abs / 3600) + "h";
+  return seconds >= 0 ? "in " + text : text + " ago";
+}
+
+// api 请求数据接口，返回 401 时提示输入令牌后重试一次
+async function api(path, options = {}, retried = false) {
+  const headers = {};
+  const token = localStorage.getItem(TOKEN_KEY);
+  if (token) headers["Authorization"] = "Bearer " + token;
+  const resp = await fetch(API + path, { ...options, headers });
+  if (resp.status === 401 && !retried) {
+    const input = prompt("API token:");
+    if (!input) throw new Error("A token is required to view this dashboard.");
+    localStorage.setItem(TOKEN_KEY, input.trim());
+    return api(path, options, true);
+  }
+  const body = await resp.json().catch(() => ({}));
+  if (!resp.ok) throw new Error(body.error || resp.status + " " + resp.statusText);
+  return body;
+}
+
+function showError(err) {
+  const node = $("error");
+  node.hidden = !err;
+  node.textContent = err ? err.message : "";
+}
+
+async function action(path, button) {
+  if (button) button.disabled = true;
+  try {
+    await api(path, { method: "POST" });
+    showError(null);
+    // 操作在主循环中异步执行，稍后刷新
+    setTimeout(refresh, 1500);
+  } catch (err) {
+    showError(err);
+  } finally {
+    if (button) button.disabled = false;
+  }
+}
+
+function renderSummary(status) {
+  const detection = $("detection");
+  detection.replaceChildren(
+    status.detection_ok ? badge("OK", "ok") : badge("Failed", "bad"),
+    el("span", " " + relative(status.last_detection), "muted"),
+  );
+  const families = Object.entries(status.detected || {});
+  $("detected").textContent = families.length
+    ? families.map(([family, ip]) => family + ": " + ip).join(", ")
+    : "-";
+  $("vote").textContent = status.vote || "-";
+  $("next-run").textContent = status.next_run ? relative(status.next_run) : "-";
+}
+
+function renderRecords(records) {
+  const rows = records.map((rec) => {
+    const tr = el("tr");
+    const state = rec.paused
+      ? badge("Paused", "warn")
+      : rec.in_sync
+        ? badge("In sync", "ok")
+        : badge("Out of sync", "bad");
+    tr.append(
+      el("td", rec.record),
+      el("td", rec.type || "-"),
+      el("td", rec.detected_ip || "-"),
+      el("td", rec.pushed_ip || "-"),
+      el("td"),
+      el("td", formatTime(rec.last_update)),
+      el("td", rec.last_error || "", "error"),
+    );
+    tr.children[4].append(state);
+    const actions = el("td", null, "action");
+    if (!readOnly) {
+      const name = encodeURIComponent(rec.record);
+      const push = el("button", "Push");
+      push.title = "Re-check the provider and write the detected IP now";
+      push.disabled = rec.paused;
+      push.onclick = () => action("/records/" + name + "/push", push);
+      const toggle = el("button", rec.paused ? "Resume" : "Pause");
+      toggle.onclick = () => action("/records/" + name + (rec.paused ? "/resume" : "/pause"), toggle);
+      actions.append(push, " ", toggle);
+    }
+    tr.append(actions);
+    return tr;
+  });
+  $("records").replaceChildren(...rows);
+}
+
+function renderSources(sources, detected) {
+  const winners = new Set(Object.values(detected || {}));
+  const rows = sources.map((src) => {
+    const tr = el("tr");
+    const ip = el("td", src.ip || "-", winners.has(src.ip) ? "voted" : "");
+    if (winners.has(src.ip)) ip.title = "Agrees with the voted IP";
+    tr.append(el("td", src.name), el("td"), ip,
+      el("td", Math.round(src.latency_seconds * 1000) + " ms"),
+      el("td", src.successes + " / " + src.failures),
+      el("td", src.error || "", "error"));
+    tr.children[1].append(src.healthy ? badge("OK", "ok") : badge("Failed", "bad"));
+    return tr;
+  });
+  if (!rows.length) {
+    const tr = el("tr");
+    const td = el("td", "No detection has run yet.", "muted");
+    td.colSpan = 6;
+    tr.append(td);
+    rows.push(tr);
+  }
+  $("sources").replaceChildren(...rows);
+}
+
+function renderHistory(events) {
+  const items = events.map((ev) => {
+    const li = el("li");
+    const time = el("time", formatTime(ev.time));
+    time.dateTime = ev.time;
+    const detail = el("span", null, "detail");
+    detail.append(badge(ev.event.replace(/_/g, " "), EVENT_CLASS[ev.event] || "muted"), " " + ev.record);
+    if (ev.new_value) detail.append(" " + (ev.old_value ? ev.old_value + " → " : "") + ev.new_value);
+    if (ev.dry_run) detail.append(" (dry-run)");
+    if (ev.lag_seconds) detail.append(el("span", " after " + Math.round(ev.lag_seconds) + "s", "muted"));
+    if (ev.error) detail.append(el("div", ev.error, "error"));
+    li.append(time, detail);
+    return li;
+  });
+  if (!items.length) items.push(el("li", "No events yet.", "muted"));
+  $("history").replaceChildren(...items);
+}
+
+async function refresh() {
+  try {
+    // 依次请求，避免需要令牌时重复提示
+    const status = await api("/status");
+    const history = await api("/history?limit=50");
+    renderSummary(status);
+    renderRecords(status.records || []);
+    renderSources(status.sources || [], status.detected);
+    renderHistory(history || []);
+    $("updated").textContent = "Last refreshed " + new Date().toLocaleTimeString() + ".";
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+  $("logout").hidden = !localStorage.getItem(TOKEN_KEY);
+}
+
+$("mode").textContent = readOnly ? "read-only" : tokenRequired ? "token required" : "";
+$("mode").hidden = !$("mode").textContent;
+$("run").onclick = (e) => action("/round", e.currentTarget);
+$("logout").onclick = () => {
+  localStorage.removeItem(TOKEN_KEY);
+  refresh();
+};
+
+refresh();
+setInterval(refresh, REFRESH_MS);
@@ -0,0 +1,273 @@
+package history
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventIPChanged       = "ip_changed"
+	EventUpdateSucceeded = "update_succeeded"
+	EventUpdateFailed    = "update_failed"
+	EventDetectionFailed = "detection_failed"
+	EventRecovered       = "recovered" // 失败后检测成功且记录已同步
+)
+
+// Events 所有事件类型
+var Events = []string{EventIPChanged, EventUpdateSucceeded, EventUpdateFailed, EventDetectionFailed, EventRecovered}
+
+// ValidEvent 判断事件名是否有效
+func ValidEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+var logWarnFunc func(string, ...interface{})
+
+// SetLogger 设置历史模块使用的日志函数
+func SetLogger(warn func(string, ...interface{})) {
+	logWarnFunc = warn
+}
+
+func logWarn(format string, a ...interface{}) {
+	if logWarnFunc != nil {
+		logWarnFunc(format, a...)
+	}
+}
+
+// Source 单个 IP 源在该轮的结果
+type Source struct {
+	Name  string `json:"name"`
+	IP    string `json:"ip,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Entry 一条历史记录，每行一个 JSON 对象
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Record     string    `json:"record"`
+	Provider   string    `json:"provider,omitempty"`
+	Type       string    `json:"type,omitempty"`
+	Event      string    `json:"event"`
+	OldValue   string    `json:"old_value,omitempty"`
+	NewValue   string    `json:"new_value,omitempty"`
+	RecordID   string    `json:"record_id,omitempty"`
+	Response   string    `json:"response,omitempty"`    // 服务商返回的变更摘要
+	LagSeconds float64   `json:"lag_seconds,omitempty"` // 从检测到新 IP 到更新成功的秒数
+	DryRun     bool      `json:"dry_run,omitempty"`
+	Vote       string    `json:"vote,omitempty"` // 投票判定方式
+	Sources    []Source  `json:"sources,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Store 只追加的 JSONL 历史文件
+type Store struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// Open 以追加方式打开历史文件，不存在时创建
+func Open(path string) (*Store, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path, file: f}, nil
+}
+
+// Path 返回历史文件路径
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append 追加一条记录，整行一次写入
+func (s *Store) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// Filter 查询条件，零值字段不过滤
+type Filter struct {
+	Record string
+	Events []string
+	Since  time.Time
+	Until  time.Time
+	Limit  int // 只保留最新的 N 条
+}
+
+// Match 判断记录是否满足条件
+func (f Filter) Match(e Entry) bool {
+	if f.Record != "" && !strings.EqualFold(e.Record, f.Record) {
+		return false
+	}
+	if len(f.Events) > 0 {
+		found := false
+		for _, ev := range f.Events {
+			if ev == e.Event {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Read 读取历史文件中满足条件的记录，按时间从旧到新返回。
+// 进程中途退出可能留下不完整的最后一行，该行会被忽略；中间无法解析的行跳过并记录一条警告
+func Read(path string, f Filter) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	// badLine 为最近一个无法解析的行号，之后还有内容时才计入跳过的行
+	var badLine, firstBad, skipped int
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if badLine != 0 {
+			if skipped == 0 {
+				firstBad = badLine
+			}
+			skipped++
+			badLine = 0
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			badLine = n
+			continue
+		}
+		if !f.Match(e) {
+			continue
+		}
+		entries = append(entries, e)
+		if f.Limit > 0 && len(entries) > 2*f.Limit {
+			entries = append(entries[:0], entries[len(entries)-f.Limit:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if skipped > 0 {
+		logWarn("Skipped %d invalid history entries in %s (first at line %d).", skipped, path, firstBad)
+	}
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[len(entries)-f.Limit:]
+	}
+	return entries, nil
+}
+
+// WriteJSON 以 JSON 数组输出
+func WriteJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// csvHeader CSV 的列，sources 合并为 name=ip 或 name!error，以分号分隔
+var csvHeader = []string{
+	"time", "record", "provider", "type", "event", "old_value", "new_value",
+	"record_id", "response", "lag_seconds", "dry_run", "vote", "sources", "error",
+}
+
+// WriteCSV 以 CSV 输出，带表头
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		lag := ""
+		if e.LagSeconds > 0 {
+			lag = strconv.FormatFloat(e.LagSeconds, 'f', 3, 64)
+		}
+		row := []string{
+			e.Time.Format(time.RFC3339), e.Record, e.Provider, e.Type, e.Event, e.OldValue, e.NewValue,
+			e.RecordID, e.Response, lag, strconv.FormatBool(e.DryRun), e.Vote, FormatSources(e.Sources), e.Error,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// FormatSources 将各来源结果合并为一个字段，如 ipify=1.2.3.4;ident!timeout
+func FormatSources(sources []Source) string {
+	parts := make([]string, 0, len(sources))
+	for _, s := range sources {
+		if s.Error != "" {
+			parts = append(parts, s.Name+"!"+s.Error)
+		} else {
+			parts = append(parts, s.Name+"="+s.IP)
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// ParseTime 解析查询时间：RFC 3339、2006-01-02、2006-01-02 15:04（本地时区），
+// 或相对于 now 的时长，如 90m、24h、7d
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected e.g. 2026-01-02, 2026-01-02T15:04:05Z, 24h or 7d", value)
+}
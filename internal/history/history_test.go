@@ -0,0 +1,247 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+
+// writeHistory 用 Store 写入记录，再追加 tail 原样内容，返回文件路径
+func writeHistory(t *testing.T, entries []Entry, tail string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := s.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if tail != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(tail)
+		f.Close()
+	}
+	return path
+}
+
+func sampleEntries() []Entry {
+	return []Entry{
+		{Time: base, Record: "home.example.com", Event: EventIPChanged, OldValue: "203.0.113.1", NewValue: "203.0.113.7"},
+		{Time: base.Add(time.Minute), Record: "home.example.com", Event: EventUpdateFailed, Error: "timeout"},
+		{Time: base.Add(2 * time.Minute), Record: "nas.example.com", Event: EventDetectionFailed,
+			Sources: []Source{{Name: "ipify", Error: "timeout"}, {Name: "ident", IP: "203.0.113.7"}}},
+		{Time: base.Add(3 * time.Minute), Record: "home.example.com", Event: EventUpdateSucceeded, NewValue: "203.0.113.7", LagSeconds: 120},
+		{Time: base.Add(4 * time.Minute), Record: "home.example.com", Event: EventRecovered},
+	}
+}
+
+func events(entries []Entry) string {
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Event
+	}
+	return strings.Join(names, ",")
+}
+
+func TestFilterMatch(t *testing.T) {
+	e := Entry{Time: base, Record: "home.example.com", Event: EventUpdateFailed}
+	tests := []struct {
+		name string
+		f    Filter
+		want bool
+	}{
+		{"zero filter", Filter{}, true},
+		{"record", Filter{Record: "home.example.com"}, true},
+		{"record case insensitive", Filter{Record: "HOME.example.com"}, true},
+		{"other record", Filter{Record: "nas.example.com"}, false},
+		{"event listed", Filter{Events: []string{EventIPChanged, EventUpdateFailed}}, true},
+		{"event not listed", Filter{Events: []string{EventIPChanged}}, false},
+		{"since inclusive", Filter{Since: base}, true},
+		{"since after", Filter{Since: base.Add(time.Second)}, false},
+		{"until exclusive", Filter{Until: base}, false},
+		{"until after", Filter{Until: base.Add(time.Second)}, true},
+		{"all conditions", Filter{Record: "home.example.com", Events: []string{EventUpdateFailed}, Since: base.Add(-time.Hour), Until: base.Add(time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.Match(e); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	path := writeHistory(t, sampleEntries(), "")
+	tests := []struct {
+		name string
+		f    Filter
+		want string
+	}{
+		{"all", Filter{}, "ip_changed,update_failed,detection_failed,update_succeeded,recovered"},
+		{"record", Filter{Record: "nas.example.com"}, "detection_failed"},
+		{"events", Filter{Events: []string{EventUpdateFailed, EventRecovered}}, "update_failed,recovered"},
+		{"since", Filter{Since: base.Add(3 * time.Minute)}, "update_succeeded,recovered"},
+		{"until", Filter{Until: base.Add(time.Minute)}, "ip_changed"},
+		{"limit keeps newest", Filter{Limit: 2}, "update_succeeded,recovered"},
+		{"limit after filter", Filter{Record: "home.example.com", Limit: 3}, "update_failed,update_succeeded,recovered"},
+		{"no match", Filter{Record: "other.example.com"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Read(path, tt.f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := events(entries); got != tt.want {
+				t.Errorf("Read() events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	entries, err := Read(path, Filter{Record: "nas.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatSources(entries[0].Sources); got != "ipify!timeout;ident=203.0.113.7" {
+		t.Errorf("sources round trip = %q", got)
+	}
+}
+
+func TestReadLimitManyEntries(t *testing.T) {
+	var entries []Entry
+	for i := 0; i < 100; i++ {
+		entries = append(entries, Entry{Time: base.Add(time.Duration(i) * time.Minute), Record: "home.example.com", Event: EventIPChanged, NewValue: strings.Repeat("x", i)})
+	}
+	got, err := Read(writeHistory(t, entries, ""), Filter{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || len(got[0].NewValue) != 97 || len(got[2].NewValue) != 99 {
+		t.Errorf("Read() with limit returned %d entries, first %d", len(got), len(got[0].NewValue))
+	}
+}
+
+func TestReadTruncatedLastLine(t *testing.T) {
+	tests := []struct {
+		name string
+		tail string
+	}{
+		{"partial json", `{"time":"2026-03-01T09:00:00Z","record":"home.exa`},
+		{"partial json with newline", `{"time":"2026-03-01T09:00:00Z","record":"home.exa` + "\n"},
+		{"trailing blank lines", "\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Read(writeHistory(t, sampleEntries(), tt.tail), Filter{})
+			if err != nil {
+				t.Fatalf("Read() error = %v, want last line ignored", err)
+			}
+			if len(entries) != len(sampleEntries()) {
+				t.Errorf("Read() returned %d entries, want %d", len(entries), len(sampleEntries()))
+			}
+		})
+	}
+}
+
+func TestReadCorruptLine(t *testing.T) {
+	var warnings []string
+	SetLogger(func(format string, a ...interface{}) { warnings = append(warnings, fmt.Sprintf(format, a...)) })
+	defer SetLogger(nil)
+
+	// 中途崩溃留下的半行之后又追加了记录
+	path := writeHistory(t, sampleEntries()[:1], "not json\n"+`{"time":"2026-03-01T09:00:00Z","rec`+"\n")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2026-03-01T09:00:00Z","record":"home.example.com","event":"recovered"}` + "\n" + `{"time":"2026-03-01T10:0`)
+	f.Close()
+
+	entries, err := Read(path, Filter{})
+	if err != nil {
+		t.Fatalf("Read() error = %v, want corrupt lines skipped", err)
+	}
+	if got := events(entries); got != "ip_changed,recovered" {
+		t.Errorf("Read() events = %q, want ip_changed,recovered", got)
+	}
+	want := "Skipped 2 invalid history entries in " + path + " (first at line 2)."
+	if len(warnings) != 1 || warnings[0] != want {
+		t.Errorf("warnings = %q, want [%q]", warnings, want)
+	}
+
+	// 只有最后一行不完整时不警告
+	warnings = nil
+	if _, err := Read(writeHistory(t, sampleEntries(), `{"time":`), Filter{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %q, want none for a trailing partial line", warnings)
+	}
+}
+
+func TestReadMissingFile(t *testing.T) {
+	if _, err := Read(filepath.Join(t.TempDir(), "missing.jsonl"), Filter{}); !os.IsNotExist(err) {
+		t.Errorf("Read() error = %v, want not exist", err)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"7d", now.AddDate(0, 0, -7)},
+		{"0d", now},
+		{"24h", now.Add(-24 * time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"1h30m", now.Add(-90 * time.Minute)},
+		{" 2h ", now.Add(-2 * time.Hour)},
+		{"2026-01-02T15:04:05Z", time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2026-01-02T15:04:05+08:00", time.Date(2026, 1, 2, 7, 4, 5, 0, time.UTC)},
+		{"2026-01-02", time.Date(2026, 1, 2, 0, 0, 0, 0, time.Local)},
+		{"2026-01-02 15:04", time.Date(2026, 1, 2, 15, 4, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+	for _, value := range []string{"", "yesterday", "-1d", "-2h", "7w", "2026-13-01", "01/02/2026"} {
+		if got, err := ParseTime(value, now); err == nil {
+			t.Errorf("ParseTime(%q) = %v, want error", value, got)
+		}
+	}
+}
+
+func TestValidEvent(t *testing.T) {
+	for _, e := range Events {
+		if !ValidEvent(e) {
+			t.Errorf("ValidEvent(%q) = false", e)
+		}
+	}
+	if ValidEvent("updated") {
+		t.Error("ValidEvent(\"updated\") = true")
+	}
+}
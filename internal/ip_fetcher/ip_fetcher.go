@@ -13,13 +13,43 @@ import (
 	"OpenDDNS/internal/config"
 )
 
-func FetchIP(src config.IPSrc) (string, error) {
-	return FetchIPWithNetwork(src, "")
+func FetchIP(ctx context.Context, src config.IPSrc) (string, error) {
+	return FetchIPWithNetwork(ctx, src, "")
+}
+
+// 校验结论
+const (
+	VerdictIPv4     = "ipv4"
+	VerdictIPv6     = "ipv6"
+	VerdictInvalid  = "invalid"         // 提取的值不是 IP 地址
+	VerdictMismatch = "family mismatch" // 地址族与强制的网络类型不符
+)
+
+// FetchResult 查询单个 IP 源的详细结果
+type FetchResult struct {
+	Source     string
+	Network    string // ipv4、ipv6 或空（自动）
+	StatusCode int
+	Latency    time.Duration
+	Raw        string // 按 type 从响应中提取出的原始值
+	IP         string // 校验通过的 IP，失败时为空
+	Verdict    string
+	Err        error
 }
 
 // FetchIPWithNetwork 获取IP地址，支持强制指定网络类型
 // networkType: "ipv4", "ipv6" 或 "" (自动)
-func FetchIPWithNetwork(src config.IPSrc, networkType string) (string, error) {
+func FetchIPWithNetwork(ctx context.Context, src config.IPSrc, networkType string) (string, error) {
+	result := FetchIPDetail(ctx, src, networkType)
+	if result.Err != nil {
+		return "", result.Err
+	}
+	return result.IP, nil
+}
+
+// FetchIPDetail 查询 IP 源并返回状态码、耗时、原始值和校验结论，ctx 取消时中止请求
+func FetchIPDetail(ctx context.Context, src config.IPSrc, networkType string) FetchResult {
+	result := FetchResult{Source: src.Name, Network: strings.ToLower(networkType)}
 	client := &http.Client{
 		Timeout: 10 * time.Second,
 	}
@@ -48,13 +78,52 @@ func FetchIPWithNetwork(src config.IPSrc, networkType string) (string, error) {
 		client.Transport = transport
 	}
 
-	resp, err := client.Get(src.URL)
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		result.Err = fmt.Errorf("fetch %s failed: %v", src.Name, err)
+		return result
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("fetch %s failed: %v", src.Name, err)
+		result.Latency = time.Since(start)
+		result.Err = fmt.Errorf("fetch %s failed: %v", src.Name, err)
+		return result
 	}
 	defer resp.Body.Close()
 	body, _ := ioutil.ReadAll(resp.Body)
+	result.Latency = time.Since(start)
+	result.StatusCode = resp.StatusCode
+
+	raw, err := extract(src, body)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Raw = raw
+
+	parsed := net.ParseIP(strings.TrimSpace(raw))
+	switch {
+	case parsed == nil:
+		result.Verdict = VerdictInvalid
+		result.Err = fmt.Errorf("%s returned invalid IP address: %q", src.Name, raw)
+	case parsed.To4() != nil:
+		result.Verdict = VerdictIPv4
+	default:
+		result.Verdict = VerdictIPv6
+	}
+	if result.Err == nil && (result.Network == "ipv4" || result.Network == "ipv6") && result.Network != result.Verdict {
+		result.Verdict = VerdictMismatch
+		result.Err = fmt.Errorf("%s returned %s address over %s: %s", src.Name, GetIPType(parsed.String()), result.Network, raw)
+	}
+	if result.Err == nil {
+		result.IP = parsed.String()
+	}
+	return result
+}
 
+// extract 按 type 从响应体中提取 IP 字段的原始值
+func extract(src config.IPSrc, body []byte) (string, error) {
 	switch src.Type {
 	case "json":
 		var m map[string]interface{}
@@ -79,7 +148,7 @@ func FetchIPWithNetwork(src config.IPSrc, networkType string) (string, error) {
 		lines := strings.Split(string(body), "\n")
 		for _, line := range lines {
 			if strings.HasPrefix(line, "ip=") {
-				return strings.TrimPrefix(line, "ip="), nil
+				return strings.TrimSpace(strings.TrimPrefix(line, "ip=")), nil
 			}
 		}
 		return "", fmt.Errorf("ip not found in trace")
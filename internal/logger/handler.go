@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// multiHandler 将每条记录分发给多个输出
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range m {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	result := make(multiHandler, len(m))
+	for i, h := range m {
+		result[i] = h.WithAttrs(attrs)
+	}
+	return result
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	result := make(multiHandler, len(m))
+	for i, h := range m {
+		result[i] = h.WithGroup(name)
+	}
+	return result
+}
+
+// field 展开后的一个结构化字段
+type field struct {
+	key   string
+	value string
+}
+
+// fieldHandler 将记录的字段展开为字符串后交给 emit 输出，供 syslog、journald 使用
+type fieldHandler struct {
+	level  slog.Leveler
+	emit   func(r slog.Record, fields []field) error
+	prefix string
+	fields []field
+}
+
+func (h *fieldHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *fieldHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := append([]field(nil), h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendField(fields, h.prefix, a)
+		return true
+	})
+	return h.emit(r, fields)
+}
+
+func (h *fieldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = append([]field(nil), h.fields...)
+	for _, a := range attrs {
+		h2.fields = appendField(h2.fields, h.prefix, a)
+	}
+	return &h2
+}
+
+func (h *fieldHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix += name + "."
+	return &h2
+}
+
+// appendField 展开字段，分组展开为 group.key
+func appendField(fields []field, prefix string, a slog.Attr) []field {
+	v := a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			fields = appendField(fields, prefix, ga)
+		}
+		return fields
+	}
+	return append(fields, field{key: prefix + a.Key, value: formatValue(v)})
+}
+
+// formatValue 将字段值格式化为字符串，时长保留到毫秒
+func formatValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339)
+	case slog.KindDuration:
+		return v.Duration().Round(time.Millisecond).String()
+	default:
+		return fmt.Sprint(v.Any())
+	}
+}
+
+// severity 将日志级别映射为 syslog/journald 优先级：
+// LogLevelDebug→7 debug，LogLevelInfo→6 info，LogLevelWarn→4 warning，LogLevelError→3 err
+func severity(l slog.Level) int {
+	switch {
+	case l >= slog.LevelError:
+		return 3
+	case l >= slog.LevelWarn:
+		return 4
+	case l >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
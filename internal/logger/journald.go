@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// journald 原生协议的 socket
+const journalSocket = "/run/systemd/journal/socket"
+
+// journalWriter 通过 journald 原生协议发送带字段的日志
+type journalWriter struct {
+	mu   sync.Mutex
+	conn net.Conn
+	tag  string
+}
+
+func dialJournal(tag string) (*journalWriter, error) {
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return nil, err
+	}
+	if tag == "" {
+		tag = "openddns"
+	}
+	return &journalWriter{conn: conn, tag: tag}, nil
+}
+
+func (j *journalWriter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.conn.Close()
+}
+
+// handler 返回写入 journald 的 slog.Handler
+func (j *journalWriter) handler() slog.Handler {
+	return &fieldHandler{level: level, emit: j.emit}
+}
+
+// emit 发送 MESSAGE、PRIORITY、SYSLOG_IDENTIFIER 和大写后的结构化字段，如 RECORD、OLD_IP
+func (j *journalWriter) emit(r slog.Record, fields []field) error {
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", r.Message)
+	writeJournalField(&buf, "PRIORITY", strconv.Itoa(severity(r.Level)))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", j.tag)
+	for _, f := range fields {
+		if name := journalName(f.key); name != "" {
+			writeJournalField(&buf, name, f.value)
+		}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err := j.conn.Write(buf.Bytes())
+	return err
+}
+
+// writeJournalField 单行值写为 KEY=value，含换行的值使用长度前缀的二进制格式
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+	buf.WriteString(name + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
+
+// journalName 字段名只允许大写字母、数字和下划线，且不能以下划线开头
+func journalName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	name = strings.TrimLeft(name, "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
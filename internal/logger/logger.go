@@ -1,10 +1,14 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
-	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -14,102 +18,318 @@ const (
 	LogLevelError = 3
 )
 
+// 日志格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// 结构化字段名，各处统一使用
+const (
+	KeyRecord   = "record"
+	KeyProvider = "provider"
+	KeySource   = "source"
+	KeyIP       = "ip"
+	KeyOldIP    = "old_ip"
+	KeyDuration = "duration"
+	KeyError    = "error"
+)
+
+// 日志输出
+const (
+	OutputStdout   = "stdout"
+	OutputFile     = "file"
+	OutputSyslog   = "syslog"
+	OutputJournald = "journald"
+)
+
 var (
-	logLevel           = LogLevelInfo
-	logFile  io.Writer = os.Stdout
+	mu        sync.RWMutex
+	level               = new(slog.LevelVar) // 默认 INFO
+	logFormat           = FormatText
+	console   io.Writer = os.Stdout
+	file      *rotatingFile
+	rotation  Rotation
+	outputs   []string // 为空时：设置了日志文件则只写文件，否则写控制台
+	sysWriter *syslogWriter
+	journal   *journalWriter
+	logger    = slog.New(newHandler())
 )
 
 var (
 	colorReset  = "\033[0m"
 	colorRed    = "\033[31m"
 	colorYellow = "\033[33m"
-	colorBlue   = "\033[34m"
 	colorGray   = "\033[90m"
 )
 
-func SetLogLevel(level string) {
-	switch level {
+func init() {
+	slog.SetDefault(logger)
+}
+
+func SetLogLevel(l string) {
+	switch l {
 	case "debug":
-		logLevel = LogLevelDebug
+		level.Set(slog.LevelDebug)
 	case "info":
-		logLevel = LogLevelInfo
+		level.Set(slog.LevelInfo)
 	case "warn":
-		logLevel = LogLevelWarn
+		level.Set(slog.LevelWarn)
 	case "error":
-		logLevel = LogLevelError
+		level.Set(slog.LevelError)
 	default:
-		logLevel = LogLevelInfo
+		level.Set(slog.LevelInfo)
+	}
+}
+
+// SetFormat 设置日志格式：text（默认，终端上带颜色）或 json
+func SetFormat(f string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.EqualFold(f, FormatJSON) {
+		logFormat = FormatJSON
+	} else {
+		logFormat = FormatText
+	}
+	rebuild()
+}
+
+// SetRotation 设置日志文件的轮转与保留规则，下次 SetLogFile 时生效
+func SetRotation(r Rotation) {
+	mu.Lock()
+	defer mu.Unlock()
+	rotation = r
+}
+
+// SetOutputs 设置日志输出，可同时使用 stdout、file、syslog、journald；
+// 无法连接的 syslog/journald 会被跳过并输出警告
+func SetOutputs(outs []string, opts SyslogOptions) {
+	mu.Lock()
+	defer mu.Unlock()
+	closeRemote()
+	outputs = nil
+	var failures []string
+	for _, out := range outs {
+		out = strings.ToLower(out)
+		switch out {
+		case OutputSyslog:
+			w, err := dialSyslog(opts)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("Failed to connect to syslog: %v", err))
+				continue
+			}
+			sysWriter = w
+		case OutputJournald:
+			j, err := dialJournal(opts.Tag)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("Failed to connect to journald: %v", err))
+				continue
+			}
+			journal = j
+		}
+		outputs = append(outputs, out)
+	}
+	rebuild()
+	for _, msg := range failures {
+		logger.Warn(msg)
+	}
+}
+
+// closeRemote 关闭 syslog/journald 连接，调用方需持有 mu
+func closeRemote() {
+	if sysWriter != nil {
+		sysWriter.Close()
+		sysWriter = nil
+	}
+	if journal != nil {
+		journal.Close()
+		journal = nil
 	}
 }
 
 func SetLogFile(path string) {
-	if path == "" {
-		logFile = os.Stdout
-		log.SetOutput(logFile)
-		return
+	mu.Lock()
+	defer mu.Unlock()
+	// 重新设置时关闭之前打开的日志文件
+	if file != nil {
+		defer file.Close()
+		file = nil
 	}
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		log.Printf("[WARN] Failed to open log file: %v, fallback to stdout", err)
-		logFile = os.Stdout
-		log.SetOutput(logFile)
-		return
+	var openErr error
+	if path != "" {
+		f, err := openRotating(path, rotation)
+		if err != nil {
+			openErr = err
+		} else {
+			file = f
+		}
+	}
+	rebuild()
+	if openErr != nil {
+		logger.Warn(fmt.Sprintf("Failed to open log file: %v, fallback to stdout", openErr))
+	}
+}
+
+// Reopen 重新打开日志文件，外部 logrotate 移走文件后调用（SIGHUP）
+func Reopen() error {
+	mu.RLock()
+	defer mu.RUnlock()
+	if file != nil {
+		return file.Reopen()
+	}
+	return nil
+}
+
+// Close 关闭日志文件和 syslog/journald 连接，恢复输出到控制台，退出前调用
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+		file = nil
 	}
-	logFile = f
-	log.SetOutput(logFile)
+	closeRemote()
+	outputs = nil
+	rebuild()
+}
+
+// UseStderr 控制台日志改为输出到标准错误，避免干扰子命令的标准输出
+func UseStderr() {
+	mu.Lock()
+	defer mu.Unlock()
+	console = os.Stderr
+	rebuild()
 }
 
-func isatty(w io.Writer) bool {
-	if runtime.GOOS == "windows" {
-		return w == os.Stdout || w == os.Stderr
+// IsTerminal 判断 w 是否为真实终端，设置了 NO_COLOR 时视为不是
+func IsTerminal(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
 	}
-	return w == os.Stdout || w == os.Stderr
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// rebuild 按当前设置重建 handler，并接管标准库 log 的输出，调用方需持有 mu
+func rebuild() {
+	logger = slog.New(newHandler())
+	slog.SetDefault(logger)
 }
 
-func colorize(level, msg string) string {
-	// 只有输出到控制台才加颜色，输出到文件不加颜色
-	if !isatty(logFile) {
-		return msg
+func newHandler() slog.Handler {
+	var handlers []slog.Handler
+	active := outputs
+	if len(active) == 0 {
+		active = []string{OutputStdout}
+		if file != nil {
+			active = []string{OutputFile}
+		}
+	}
+	for _, out := range active {
+		switch out {
+		case OutputStdout:
+			handlers = append(handlers, formatHandler(console))
+		case OutputFile:
+			if file != nil {
+				handlers = append(handlers, formatHandler(file))
+			}
+		case OutputSyslog:
+			if sysWriter != nil {
+				handlers = append(handlers, sysWriter.handler())
+			}
+		case OutputJournald:
+			if journal != nil {
+				handlers = append(handlers, journal.handler())
+			}
+		}
 	}
-	switch level {
-	case "DEBUG":
-		return colorGray + msg + colorReset
-	case "INFO":
-		return msg // INFO 不加颜色
-	case "WARN":
-		return colorYellow + msg + colorReset
-	case "ERROR":
-		return colorRed + msg + colorReset
+	var h slog.Handler
+	switch len(handlers) {
+	case 0:
+		// 所有输出都不可用时回退到控制台
+		h = formatHandler(console)
+	case 1:
+		h = handlers[0]
 	default:
-		return msg
+		h = multiHandler(handlers)
 	}
+	return redactHandler{next: h}
 }
 
-func Debug(format string, a ...interface{}) {
-	if logLevel <= LogLevelDebug {
-		log.SetOutput(logFile)
-		msg := colorize("DEBUG", "[DEBUG] "+format)
-		log.Printf(msg, a...)
+// formatHandler 按 log_format 创建写入 w 的 handler
+func formatHandler(w io.Writer) slog.Handler {
+	if logFormat == FormatJSON {
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: jsonAttr})
+	}
+	return &textHandler{
+		mu:    new(sync.Mutex),
+		w:     w,
+		level: level,
+		color: IsTerminal(w),
 	}
 }
-func Info(format string, a ...interface{}) {
-	if logLevel <= LogLevelInfo {
-		log.SetOutput(logFile)
-		msg := colorize("INFO", "[INFO] "+format)
-		log.Printf(msg, a...)
+
+// jsonAttr 将时长输出为秒数，错误输出为字符串
+func jsonAttr(_ []string, a slog.Attr) slog.Attr {
+	switch v := a.Value.Any().(type) {
+	case time.Duration:
+		return slog.Float64(a.Key, v.Seconds())
+	case error:
+		return slog.String(a.Key, v.Error())
 	}
+	return a
 }
-func Warn(format string, a ...interface{}) {
-	if logLevel <= LogLevelWarn {
-		log.SetOutput(logFile)
-		msg := colorize("WARN", "[WARN] "+format)
-		log.Printf(msg, a...)
+
+// Entry 带有固定结构化字段的日志记录器
+type Entry struct {
+	attrs []any
+}
+
+// With 返回附带字段的记录器，args 为键值对或 slog.Attr
+func With(args ...any) *Entry {
+	return &Entry{attrs: args}
+}
+
+// With 在已有字段上追加字段
+func (e *Entry) With(args ...any) *Entry {
+	attrs := make([]any, 0, len(e.attrs)+len(args))
+	attrs = append(attrs, e.attrs...)
+	return &Entry{attrs: append(attrs, args...)}
+}
+
+func (e *Entry) Debug(format string, a ...interface{}) { write(slog.LevelDebug, e.attrs, format, a) }
+func (e *Entry) Info(format string, a ...interface{})  { write(slog.LevelInfo, e.attrs, format, a) }
+func (e *Entry) Warn(format string, a ...interface{})  { write(slog.LevelWarn, e.attrs, format, a) }
+func (e *Entry) Error(format string, a ...interface{}) { write(slog.LevelError, e.attrs, format, a) }
+
+func write(l slog.Level, attrs []any, format string, a []interface{}) {
+	mu.RLock()
+	current := logger
+	mu.RUnlock()
+	ctx := context.Background()
+	if !current.Enabled(ctx, l) {
+		return
 	}
+	msg := format
+	if len(a) > 0 {
+		msg = fmt.Sprintf(format, a...)
+	}
+	current.Log(ctx, l, msg, attrs...)
+}
+
+func Debug(format string, a ...interface{}) {
+	write(slog.LevelDebug, nil, format, a)
+}
+func Info(format string, a ...interface{}) {
+	write(slog.LevelInfo, nil, format, a)
+}
+func Warn(format string, a ...interface{}) {
+	write(slog.LevelWarn, nil, format, a)
 }
 func Error(format string, a ...interface{}) {
-	if logLevel <= LogLevelError {
-		log.SetOutput(logFile)
-		msg := colorize("ERROR", "[ERROR] "+format)
-		log.Printf(msg, a...)
-	}
+	write(slog.LevelError, nil, format, a)
 }
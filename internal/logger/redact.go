@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 替换敏感内容的占位符
+const redacted = "[REDACTED]"
+
+// 短于该长度的值不作为凭证屏蔽，避免误伤普通文本
+const minSecretLength = 6
+
+var (
+	secretsMu sync.RWMutex
+	secrets   *strings.Replacer
+)
+
+var (
+	// Authorization / Proxy-Authorization 头，保留认证方式
+	authHeaderPattern = regexp.MustCompile(`(?i)((?:proxy-)?authorization["']?\s*[:=]\s*["']?(?:bearer|basic|token|apikey)?\s*)[^\s"',}&]+`)
+	// X-Auth-Key、X-Auth-Token 等凭证头
+	authKeyPattern = regexp.MustCompile(`(?i)(x-auth-(?:key|token|email)["']?\s*[:=]\s*["']?)[^\s"',}&]+`)
+	// 签名和凭证类查询参数或 key=value，如阿里云 Signature、AccessKeyId，钉钉 sign、access_token
+	queryParamPattern = regexp.MustCompile(`(?i)\b((?:signature|sign|x-amz-signature|x-amz-credential|accesskeyid|access_key_id|accesskeysecret|access_key_secret|securitytoken|access_token|token|api_key|apikey|api_token|key|secret|password)=)[^&\s"',]+`)
+	// JSON 中的凭证字段，如 "AccessKeyId":"..."
+	jsonFieldPattern = regexp.MustCompile(`(?i)("(?:accesskeyid|access_key_id|accesskeysecret|access_key_secret|securitytoken|api_token|apitoken|access_token|token|client_secret|password|secret)"\s*:\s*")[^"]+`)
+)
+
+// SetSecrets 设置需要在日志中屏蔽的凭证值，替换之前设置的值
+func SetSecrets(values ...string) {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < minSecretLength || seen[v] {
+			continue
+		}
+		seen[v] = true
+		unique = append(unique, v)
+	}
+	// 先替换较长的值，避免一个凭证是另一个的子串时只屏蔽一部分
+	sort.Slice(unique, func(i, j int) bool { return len(unique[i]) > len(unique[j]) })
+	pairs := make([]string, 0, len(unique)*2)
+	for _, v := range unique {
+		pairs = append(pairs, v, redacted)
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if len(pairs) == 0 {
+		secrets = nil
+		return
+	}
+	secrets = strings.NewReplacer(pairs...)
+}
+
+// Redact 屏蔽文本中已设置的凭证、Authorization 头和签名类查询参数
+func Redact(s string) string {
+	secretsMu.RLock()
+	r := secrets
+	secretsMu.RUnlock()
+	if r != nil {
+		s = r.Replace(s)
+	}
+	s = authHeaderPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = authKeyPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = queryParamPattern.ReplaceAllString(s, "${1}"+redacted)
+	return jsonFieldPattern.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// redactHandler 在交给各输出之前屏蔽消息和字段中的敏感内容，所有级别都生效
+type redactHandler struct {
+	next slog.Handler
+}
+
+func (h redactHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = redactAttr(a)
+	}
+	return redactHandler{next: h.next.WithAttrs(clean)}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{next: h.next.WithGroup(name)}
+}
+
+// redactAttr 屏蔽字段值，error 等非字符串值按其文本处理，时长和数字保持原样
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		clean := make([]any, len(group))
+		for i, ga := range group {
+			clean[i] = redactAttr(ga)
+		}
+		return slog.Group(a.Key, clean...)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+		return slog.String(a.Key, Redact(formatValue(v)))
+	default:
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	SetSecrets()
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bearer header", "Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED]"},
+		{"basic header", "authorization: Basic dXNlcjpwYXNz", "authorization: Basic [REDACTED]"},
+		{"raw header value", "Authorization: abc123", "Authorization: [REDACTED]"},
+		{"proxy header", "Proxy-Authorization: Bearer abc", "Proxy-Authorization: Bearer [REDACTED]"},
+		{"header in json", `{"Authorization":"Bearer abc123"}`, `{"Authorization":"Bearer [REDACTED]"}`},
+		{"x-auth-key", "X-Auth-Key: 0123456789abcdef", "X-Auth-Key: [REDACTED]"},
+		{"aliyun query", "GET /?AccessKeyId=LTAI5tExample&Action=DescribeDomainRecords&Signature=abc%2Bdef%3D",
+			"GET /?AccessKeyId=[REDACTED]&Action=DescribeDomainRecords&Signature=[REDACTED]"},
+		{"dingtalk query", "https://oapi.dingtalk.com/robot/send?access_token=abc&timestamp=1&sign=xyz",
+			"https://oapi.dingtalk.com/robot/send?access_token=[REDACTED]&timestamp=1&sign=[REDACTED]"},
+		{"param inside word", "design=modern", "design=modern"},
+		{"json token fields", `{"AccessKeyId":"LTAI5tExample","api_token":"cf-abc","name":"home"}`,
+			`{"AccessKeyId":"[REDACTED]","api_token":"[REDACTED]","name":"home"}`},
+		{"json spaced", `{"password" : "hunter2"}`, `{"password" : "[REDACTED]"}`},
+		{"plain text", "updated home.example.com to 203.0.113.7", "updated home.example.com to 203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSecrets(t *testing.T) {
+	defer SetSecrets()
+	SetSecrets("cf-token-123456", "cf-token-123456-long", "short", "  padded-secret  ", "")
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"token cf-token-123456 failed", "token [REDACTED] failed"},
+		// 较长的值优先，不留下后缀
+		{"token cf-token-123456-long failed", "token [REDACTED] failed"},
+		// 短于 minSecretLength 的值不屏蔽
+		{"short answer", "short answer"},
+		{"value padded-secret here", "value [REDACTED] here"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// 再次设置时替换之前的值
+	SetSecrets("another-secret")
+	if got := Redact("cf-token-123456 another-secret"); got != "cf-token-123456 [REDACTED]" {
+		t.Errorf("after reset: %q", got)
+	}
+	SetSecrets()
+	if got := Redact("another-secret"); got != "another-secret" {
+		t.Errorf("after clear: %q", got)
+	}
+}
+
+func TestRedactHandler(t *testing.T) {
+	defer SetSecrets()
+	SetSecrets("cf-token-123456")
+	var buf bytes.Buffer
+	log := slog.New(redactHandler{next: slog.NewTextHandler(&buf, nil)})
+	log.With("token", "cf-token-123456").Info("request Authorization: Bearer abc",
+		"err", errors.New("401 for cf-token-123456"),
+		slog.Group("req", "url", "https://x/?sign=abc"),
+		"attempt", 3,
+	)
+	out := buf.String()
+	for _, leak := range []string{"cf-token-123456", "Bearer abc", "sign=abc"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("output leaks %q: %s", leak, out)
+		}
+	}
+	if !strings.Contains(out, "attempt=3") {
+		t.Errorf("non-string attrs should be kept: %s", out)
+	}
+}
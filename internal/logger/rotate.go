@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotation 日志文件轮转与保留设置，各项为 0 表示不启用
+type Rotation struct {
+	MaxSizeMB   int  // 超过该大小时轮转
+	RotateHours int  // 每隔多少小时轮转（按 UTC 时间对齐）
+	MaxBackups  int  // 最多保留的旧日志个数
+	MaxAgeDays  int  // 删除早于该天数的旧日志
+	Compress    bool // 用 gzip 压缩旧日志
+}
+
+// 旧日志文件名中的时间格式，如 openddns-20261019T112000.000.log
+const backupTimeFormat = "20060102T150405.000"
+
+// rotatingFile 按大小或时间轮转的日志文件
+type rotatingFile struct {
+	path string
+	rot  Rotation
+	now  func() time.Time // 测试时替换
+
+	mu        sync.Mutex
+	f         *os.File
+	size      int64
+	lastWrite time.Time
+
+	cleanMu  sync.Mutex     // 串行化后台清理
+	cleaning sync.WaitGroup // 进行中的后台清理，测试时等待
+}
+
+func openRotating(path string, rot Rotation) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, rot: rot, now: time.Now}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	r.goCleanup()
+	return r, nil
+}
+
+// open 以追加方式打开日志文件，记录已有大小和最后写入时间，调用方需持有 mu（或尚未共享）
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.size = fi.Size()
+	r.lastWrite = fi.ModTime()
+	if r.size == 0 {
+		r.lastWrite = r.now()
+	}
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	now := r.now()
+	if r.size > 0 && r.due(now, int64(len(p))) {
+		if err := r.rotate(now); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] Failed to rotate log file: %v\n", err)
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	r.lastWrite = now
+	return n, err
+}
+
+// due 判断写入前是否需要轮转
+func (r *rotatingFile) due(now time.Time, n int64) bool {
+	if r.rot.MaxSizeMB > 0 && r.size+n > int64(r.rot.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	if r.rot.RotateHours > 0 {
+		period := time.Duration(r.rot.RotateHours) * time.Hour
+		return !now.Truncate(period).Equal(r.lastWrite.Truncate(period))
+	}
+	return false
+}
+
+// rotate 将当前文件改名为带时间戳的旧日志并重新打开，调用方需持有 mu
+func (r *rotatingFile) rotate(now time.Time) error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+	ext := filepath.Ext(r.path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.path, ext), now.Format(backupTimeFormat), ext)
+	renameErr := os.Rename(r.path, backup)
+	if err := r.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	r.goCleanup()
+	return nil
+}
+
+// Reopen 关闭并重新打开日志文件，配合外部 logrotate 使用
+func (r *rotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f != nil {
+		r.f.Close()
+		r.f = nil
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// backupFile 一个旧日志文件
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// backups 列出当前日志的旧日志文件，按时间从新到旧排序
+func (r *rotatingFile) backups() ([]backupFile, error) {
+	dir := filepath.Dir(r.path)
+	ext := filepath.Ext(r.path)
+	prefix := strings.TrimSuffix(filepath.Base(r.path), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimPrefix(stamp, prefix), time.Local)
+		if err != nil {
+			continue
+		}
+		result = append(result, backupFile{path: filepath.Join(dir, name), time: t})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].time.After(result[j].time) })
+	return result, nil
+}
+
+// goCleanup 在后台执行 cleanup
+func (r *rotatingFile) goCleanup() {
+	r.cleaning.Add(1)
+	go func() {
+		defer r.cleaning.Done()
+		r.cleanup()
+	}()
+}
+
+// cleanup 按保留设置删除旧日志，并压缩未压缩的旧日志
+func (r *rotatingFile) cleanup() {
+	r.cleanMu.Lock()
+	defer r.cleanMu.Unlock()
+	files, err := r.backups()
+	if err != nil {
+		return
+	}
+	cutoff := r.now().AddDate(0, 0, -r.rot.MaxAgeDays)
+	for i, b := range files {
+		expired := r.rot.MaxAgeDays > 0 && b.time.Before(cutoff)
+		if expired || (r.rot.MaxBackups > 0 && i >= r.rot.MaxBackups) {
+			os.Remove(b.path)
+			continue
+		}
+		if r.rot.Compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := compressFile(b.path); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] Failed to compress log file %s: %v\n", b.path, err)
+			}
+		}
+	}
+}
+
+// compressFile 将文件压缩为 .gz 并删除原文件
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
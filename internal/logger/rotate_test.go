@@ -0,0 +1,233 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var rotateBase = time.Date(2026, 3, 1, 10, 10, 0, 0, time.UTC)
+
+// backupName 返回 app.log 在 t 时刻轮转出的旧日志文件名
+func backupName(t time.Time) string {
+	return "app-" + t.Format(backupTimeFormat) + ".log"
+}
+
+// readLogDir 读取目录下所有文件的内容，.gz 文件解压后返回
+func readLogDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if strings.HasSuffix(e.Name(), ".gz") {
+			zr, err := gzip.NewReader(f)
+			if err != nil {
+				t.Fatalf("%s: %v", e.Name(), err)
+			}
+			r = zr
+		}
+		data, err := io.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", e.Name(), err)
+		}
+		files[e.Name()] = string(data)
+	}
+	return files
+}
+
+// openTestRotating 以可控时钟打开 dir/app.log，返回文件和用于设置当前时间的函数
+func openTestRotating(t *testing.T, dir string, rot Rotation) (*rotatingFile, func(time.Time)) {
+	t.Helper()
+	// 后台清理也会读取时钟
+	var now atomic.Int64
+	now.Store(rotateBase.UnixNano())
+	clock := func() time.Time { return time.Unix(0, now.Load()).UTC() }
+	r := &rotatingFile{path: filepath.Join(dir, "app.log"), rot: rot, now: clock}
+	if err := r.open(); err != nil {
+		t.Fatal(err)
+	}
+	return r, func(t time.Time) { now.Store(t.UnixNano()) }
+}
+
+func TestRotatingFile(t *testing.T) {
+	big := func(c string) string { return strings.Repeat(c, 700*1024) }
+	type write struct {
+		at   time.Duration
+		data string
+	}
+	tests := []struct {
+		name     string
+		rot      Rotation
+		existing map[string]string
+		writes   []write
+		want     map[string]string
+	}{
+		{
+			name:   "no rotation",
+			writes: []write{{0, "a\n"}, {48 * time.Hour, "b\n"}},
+			want:   map[string]string{"app.log": "a\nb\n"},
+		},
+		{
+			name:   "size",
+			rot:    Rotation{MaxSizeMB: 1},
+			writes: []write{{0, big("a")}, {time.Minute, big("b")}, {2 * time.Minute, "c"}},
+			want: map[string]string{
+				"app.log":                               big("b") + "c",
+				backupName(rotateBase.Add(time.Minute)): big("a"),
+			},
+		},
+		{
+			name:     "existing file counts toward size",
+			rot:      Rotation{MaxSizeMB: 1},
+			existing: map[string]string{"app.log": big("a")},
+			writes:   []write{{0, big("b")}},
+			want: map[string]string{
+				"app.log":              big("b"),
+				backupName(rotateBase): big("a"),
+			},
+		},
+		{
+			name:   "time",
+			rot:    Rotation{RotateHours: 1},
+			writes: []write{{0, "a\n"}, {40 * time.Minute, "b\n"}, {55 * time.Minute, "c\n"}},
+			want: map[string]string{
+				"app.log": "c\n",
+				backupName(rotateBase.Add(55 * time.Minute)): "a\nb\n",
+			},
+		},
+		{
+			name:     "max backups keeps newest",
+			rot:      Rotation{RotateHours: 1, MaxBackups: 2},
+			existing: map[string]string{"app-notes.log": "not a backup", "other.log": "other"},
+			writes:   []write{{0, "0"}, {time.Hour, "1"}, {2 * time.Hour, "2"}, {3 * time.Hour, "3"}},
+			want: map[string]string{
+				"app.log": "3",
+				backupName(rotateBase.Add(2 * time.Hour)): "1",
+				backupName(rotateBase.Add(3 * time.Hour)): "2",
+				"app-notes.log": "not a backup",
+				"other.log":     "other",
+			},
+		},
+		{
+			name: "max age",
+			rot:  Rotation{MaxAgeDays: 7},
+			existing: map[string]string{
+				backupName(rotateBase.AddDate(0, 0, -10)):         "old",
+				backupName(rotateBase.AddDate(0, 0, -10)) + ".gz": "",
+				backupName(rotateBase.AddDate(0, 0, -3)):          "recent",
+			},
+			writes: []write{{0, "a"}},
+			want: map[string]string{
+				"app.log":                                "a",
+				backupName(rotateBase.AddDate(0, 0, -3)): "recent",
+			},
+		},
+		{
+			name:   "compress",
+			rot:    Rotation{RotateHours: 1, Compress: true},
+			writes: []write{{0, "a\n"}, {time.Hour, "b\n"}},
+			want: map[string]string{
+				"app.log": "b\n",
+				backupName(rotateBase.Add(time.Hour)) + ".gz": "a\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, data := range tt.existing {
+				if strings.HasSuffix(name, ".gz") {
+					var b strings.Builder
+					zw := gzip.NewWriter(&b)
+					zw.Write([]byte(data))
+					zw.Close()
+					data = b.String()
+				}
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r, setNow := openTestRotating(t, dir, tt.rot)
+			for _, w := range tt.writes {
+				setNow(rotateBase.Add(w.at))
+				if _, err := r.Write([]byte(w.data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r.cleaning.Wait()
+			r.cleanup()
+			r.Close()
+			if got := readLogDir(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files:\n%s\nwant:\n%s", summarize(got), summarize(tt.want))
+			}
+		})
+	}
+}
+
+// summarize 输出文件名和内容开头，避免大文件刷屏
+func summarize(files map[string]string) string {
+	var b strings.Builder
+	for name, data := range files {
+		if len(data) > 20 {
+			data = data[:20] + "..."
+		}
+		b.WriteString("  " + name + ": " + data + "\n")
+	}
+	return b.String()
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	r, setNow := openTestRotating(t, dir, Rotation{RotateHours: 24})
+	defer r.Close()
+	if _, err := r.Write([]byte("before\n")); err != nil {
+		t.Fatal(err)
+	}
+	// 外部 logrotate 改名后通知重新打开
+	path := filepath.Join(dir, "app.log")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if r.size != 0 || !r.lastWrite.Equal(rotateBase) {
+		t.Errorf("after reopen: size = %d, lastWrite = %v, want 0, %v", r.size, r.lastWrite, rotateBase)
+	}
+	setNow(rotateBase.Add(time.Hour))
+	if _, err := r.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"app.log": "after\n", "app.log.1": "before\n"}
+	if got := readLogDir(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("files:\n%s\nwant:\n%s", summarize(got), summarize(want))
+	}
+
+	// 重新打开未改名的文件时接着已有内容计算大小
+	if err := r.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if r.size != int64(len("after\n")) {
+		t.Errorf("size after reopening existing file = %d, want %d", r.size, len("after\n"))
+	}
+
+	r.Close()
+	if _, err := r.Write([]byte("closed\n")); err != os.ErrClosed {
+		t.Errorf("Write after Close error = %v, want os.ErrClosed", err)
+	}
+}
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SyslogOptions syslog 输出设置
+type SyslogOptions struct {
+	Network  string // 空或 unix 为本机 syslog，udp/tcp 发送到 Address
+	Address  string // 远程地址 host:port，本机时可指定 socket 路径
+	Tag      string // APP-NAME，默认 openddns
+	Facility string // 默认 daemon
+}
+
+// 本机 syslog socket 的常见位置
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// ValidFacility 判断 syslog facility 名称是否有效，空值视为默认值
+func ValidFacility(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, ok := facilities[strings.ToLower(name)]
+	return ok
+}
+
+// syslogWriter 以 RFC 5424 格式发送日志，TCP 使用 RFC 6587 八位组计数分帧
+type syslogWriter struct {
+	mu       sync.Mutex
+	opts     SyslogOptions
+	conn     net.Conn
+	stream   bool // 面向流的连接需要分帧
+	hostname string
+	facility int
+}
+
+func dialSyslog(opts SyslogOptions) (*syslogWriter, error) {
+	if opts.Tag == "" {
+		opts.Tag = "openddns"
+	}
+	facility, ok := facilities[strings.ToLower(opts.Facility)]
+	if !ok {
+		facility = facilities["daemon"]
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	w := &syslogWriter{opts: opts, hostname: hostname, facility: facility}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect 建立到 syslog 的连接，调用方需持有 mu（或尚未共享）
+func (w *syslogWriter) connect() error {
+	switch strings.ToLower(w.opts.Network) {
+	case "udp", "tcp":
+		network := strings.ToLower(w.opts.Network)
+		conn, err := net.Dial(network, w.opts.Address)
+		if err != nil {
+			return err
+		}
+		w.conn, w.stream = conn, network == "tcp"
+		return nil
+	case "", "unix":
+		sockets := localSyslogSockets
+		if w.opts.Address != "" {
+			sockets = []string{w.opts.Address}
+		}
+		var lastErr error
+		for _, path := range sockets {
+			for _, network := range []string{"unixgram", "unix"} {
+				conn, err := net.Dial(network, path)
+				if err == nil {
+					w.conn, w.stream = conn, network == "unix"
+					return nil
+				}
+				lastErr = err
+			}
+		}
+		return fmt.Errorf("no local syslog socket available: %v", lastErr)
+	default:
+		return fmt.Errorf("unsupported syslog network %q", w.opts.Network)
+	}
+}
+
+// send 发送一条消息，连接断开时重连一次
+func (w *syslogWriter) send(msg string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err = w.connect(); err != nil {
+				continue
+			}
+		}
+		frame := msg
+		if w.stream {
+			frame = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+		if _, err = w.conn.Write([]byte(frame)); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return err
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// handler 返回写入该 syslog 的 slog.Handler
+func (w *syslogWriter) handler() slog.Handler {
+	return &fieldHandler{level: level, emit: w.emit}
+}
+
+// emit 生成 RFC 5424 消息：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID - MSG。
+// 没有注册的企业编号，不使用结构化数据，字段以 key=value 附加在消息末尾
+func (w *syslogWriter) emit(r slog.Record, fields []field) error {
+	pri := w.facility*8 + severity(r.Level)
+	var b strings.Builder
+	b.WriteString(r.Message)
+	for _, f := range fields {
+		value := f.value
+		if needsQuote(value) {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, " %s=%s", f.key, value)
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		pri, r.Time.Format("2006-01-02T15:04:05.000000Z07:00"), w.hostname, w.opts.Tag, os.Getpid(), b.String())
+	if err := w.send(msg); err != nil {
+		return fmt.Errorf("syslog write failed: %w", err)
+	}
+	return nil
+}
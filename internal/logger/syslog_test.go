@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSyslogMessage(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	w, err := dialSyslog(SyslogOptions{Network: "udp", Address: pc.LocalAddr().String(), Facility: "local0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	log := slog.New(w.handler())
+	log.With("record", "home.example.com").Warn("Update failed.", "ip", "203.0.113.7", "error", "timeout after 10s", "empty", "")
+
+	buf := make([]byte, 2048)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	// local0 (16) * 8 + warning (4) = 132；结构化数据为 "-"，字段附加在消息末尾
+	want := regexp.MustCompile(fmt.Sprintf(
+		`^<132>1 \S+ \S+ openddns %d - - Update failed\. record=home\.example\.com ip=203\.0\.113\.7 error="timeout after 10s" empty=""$`,
+		os.Getpid()))
+	if !want.MatchString(got) {
+		t.Errorf("syslog message:\n got %q\nwant %s", got, want)
+	}
+}
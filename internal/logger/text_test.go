@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+var logTime = time.Date(2026, 3, 1, 10, 10, 5, 0, time.UTC)
+
+// newTestText 返回写入 buf 的文本 handler
+func newTestText(buf *bytes.Buffer, l slog.Level, color bool) *textHandler {
+	return &textHandler{mu: new(sync.Mutex), w: buf, level: l, color: color}
+}
+
+// logRecord 以固定时间输出一条记录
+func logRecord(t *testing.T, h slog.Handler, l slog.Level, msg string, attrs ...slog.Attr) {
+	t.Helper()
+	if !h.Enabled(context.Background(), l) {
+		return
+	}
+	r := slog.NewRecord(logTime, l, msg, 0)
+	r.AddAttrs(attrs...)
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTextHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		with  func(h slog.Handler) slog.Handler // 为空时直接使用 handler
+		level slog.Level
+		attrs []slog.Attr
+		want  string
+	}{
+		{"message only", nil, slog.LevelInfo, nil,
+			"2026/03/01 10:10:05 [INFO] Sync finished.\n"},
+		{"plain values", nil, slog.LevelInfo,
+			[]slog.Attr{slog.String("ip", "203.0.113.7"), slog.Int("attempt", 2), slog.Bool("dry_run", false)},
+			"2026/03/01 10:10:05 [INFO] Sync finished. ip=203.0.113.7 attempt=2 dry_run=false\n"},
+		{"quoted values", nil, slog.LevelWarn,
+			[]slog.Attr{slog.String("error", "timeout after 10s"), slog.String("empty", ""), slog.String("body", `a="b"`), slog.String("ctrl", "a\x01b")},
+			`2026/03/01 10:10:05 [WARN] Sync finished. error="timeout after 10s" empty="" body="a=\"b\"" ctrl="a\x01b"` + "\n"},
+		{"time, duration and error", nil, slog.LevelError,
+			[]slog.Attr{slog.Time("at", logTime.Add(time.Hour)), slog.Duration("took", 1234567*time.Microsecond), slog.Any("error", errors.New("HTTP 403"))},
+			"2026/03/01 10:10:05 [ERROR] Sync finished. at=2026-03-01T11:10:05Z took=1.235s error=\"HTTP 403\"\n"},
+		{"inline group and empty attr", nil, slog.LevelInfo,
+			[]slog.Attr{slog.Group("source", slog.String("name", "ipify"), slog.String("ip", "203.0.113.7")), {}, slog.Group("", slog.Int("n", 1))},
+			"2026/03/01 10:10:05 [INFO] Sync finished. source.name=ipify source.ip=203.0.113.7 n=1\n"},
+		{"with attrs", func(h slog.Handler) slog.Handler {
+			return h.WithAttrs([]slog.Attr{slog.String("record", "home.example.com")})
+		}, slog.LevelInfo, []slog.Attr{slog.String("ip", "203.0.113.7")},
+			"2026/03/01 10:10:05 [INFO] Sync finished. record=home.example.com ip=203.0.113.7\n"},
+		{"with group", func(h slog.Handler) slog.Handler {
+			return h.WithAttrs([]slog.Attr{slog.String("record", "home.example.com")}).WithGroup("http").WithAttrs([]slog.Attr{slog.String("method", "POST")}).WithGroup("")
+		}, slog.LevelInfo, []slog.Attr{slog.Int("status", 202), slog.Group("client", slog.String("addr", "127.0.0.1"))},
+			"2026/03/01 10:10:05 [INFO] Sync finished. record=home.example.com http.method=POST http.status=202 http.client.addr=127.0.0.1\n"},
+		{"below level", nil, slog.LevelDebug, []slog.Attr{slog.Int("n", 1)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var h slog.Handler = newTestText(&buf, slog.LevelInfo, false)
+			if tt.with != nil {
+				h = tt.with(h)
+			}
+			logRecord(t, h, tt.level, "Sync finished.", tt.attrs...)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextHandlerColor(t *testing.T) {
+	var buf bytes.Buffer
+	h := newTestText(&buf, slog.LevelDebug, true)
+	for _, l := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		logRecord(t, h, l, "m")
+	}
+	want := colorGray + "2026/03/01 10:10:05 [DEBUG] m" + colorReset + "\n" +
+		"2026/03/01 10:10:05 [INFO] m\n" +
+		colorYellow + "2026/03/01 10:10:05 [WARN] m" + colorReset + "\n" +
+		colorRed + "2026/03/01 10:10:05 [ERROR] m" + colorReset + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestMultiHandler(t *testing.T) {
+	// 控制台输出 DEBUG 及以上，文件以 JSON 只输出 WARN 及以上
+	var text, js bytes.Buffer
+	h := multiHandler{
+		newTestText(&text, slog.LevelDebug, false),
+		slog.NewJSONHandler(&js, &slog.HandlerOptions{Level: slog.LevelWarn, ReplaceAttr: jsonAttr}),
+	}
+	if h.Enabled(context.Background(), slog.LevelDebug-1) {
+		t.Error("multiHandler enabled below every output's level")
+	}
+	with := h.WithAttrs([]slog.Attr{slog.String("record", "home.example.com")}).WithGroup("dns")
+	logRecord(t, with, slog.LevelDebug, "Checking record.")
+	logRecord(t, with, slog.LevelWarn, "Update failed.", slog.Duration("took", 1500*time.Millisecond), slog.Any("error", errors.New("HTTP 403")))
+
+	wantText := "2026/03/01 10:10:05 [DEBUG] Checking record. record=home.example.com\n" +
+		"2026/03/01 10:10:05 [WARN] Update failed. record=home.example.com dns.took=1.5s dns.error=\"HTTP 403\"\n"
+	if got := text.String(); got != wantText {
+		t.Errorf("text output:\n got %q\nwant %q", got, wantText)
+	}
+	wantJSON := `{"time":"2026-03-01T10:10:05Z","level":"WARN","msg":"Update failed.","record":"home.example.com","dns":{"took":1.5,"error":"HTTP 403"}}` + "\n"
+	if got := js.String(); got != wantJSON {
+		t.Errorf("json output:\n got %s\nwant %s", got, wantJSON)
+	}
+}
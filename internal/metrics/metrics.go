@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "openddns"
+
+// 检测轮次结果标签
+const (
+	RoundUnchanged       = "unchanged"
+	RoundUpdated         = "updated"
+	RoundDetectionFailed = "detection_failed"
+	RoundProviderFailed  = "provider_failed"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "Build information, value is always 1.",
+	}, []string{"version", "build_time"})
+
+	detectionRounds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "detection_rounds_total",
+		Help:      "Detection rounds by result.",
+	}, []string{"result"})
+
+	sourceRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ip_source_requests_total",
+		Help:      "IP source requests by source and result (success or failure).",
+	}, []string{"source", "result"})
+
+	sourceDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ip_source_duration_seconds",
+		Help:      "IP source request latency.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10},
+	}, []string{"source"})
+
+	voteConflicts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "vote_conflicts_total",
+		Help:      "Rounds where IP sources disagreed and the priority order decided.",
+	})
+
+	updateAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "record_update_attempts_total",
+		Help:      "DNS record update attempts.",
+	}, []string{"record"})
+
+	updateSuccesses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "record_update_successes_total",
+		Help:      "Successful DNS record updates, including records already up to date.",
+	}, []string{"record"})
+
+	updateFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "record_update_failures_total",
+		Help:      "Failed DNS record updates.",
+	}, []string{"record"})
+
+	currentIP = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "current_ip_info",
+		Help:      "Currently detected public IP per record, value is always 1.",
+	}, []string{"record", "family", "ip"})
+
+	lastIPChange = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_ip_change_timestamp_seconds",
+		Help:      "Unix time when a new public IP was detected for the record.",
+	}, []string{"record"})
+
+	lastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_update_timestamp_seconds",
+		Help:      "Unix time when the record was last confirmed in sync with the detected IP.",
+	}, []string{"record"})
+
+	providerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "DNS provider API call latency by operation and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "operation", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildInfo, detectionRounds, sourceRequests, sourceDuration, voteConflicts,
+		updateAttempts, updateSuccesses, updateFailures,
+		currentIP, lastIPChange, lastSuccess, providerDuration,
+	)
+}
+
+// Handler 返回 /metrics 的 HTTP handler
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// SetBuildInfo 记录版本信息
+func SetBuildInfo(version, buildTime string) {
+	buildInfo.WithLabelValues(version, buildTime).Set(1)
+}
+
+// ObserveRound 统计一轮检测的结果
+func ObserveRound(result string) {
+	detectionRounds.WithLabelValues(result).Inc()
+}
+
+// ObserveSource 统计一次 IP 源请求
+func ObserveSource(source string, d time.Duration, ok bool) {
+	result := "success"
+	if !ok {
+		result = "failure"
+	}
+	sourceRequests.WithLabelValues(source, result).Inc()
+	sourceDuration.WithLabelValues(source).Observe(d.Seconds())
+}
+
+// ObserveVoteConflict 统计一次来源结果不一致
+func ObserveVoteConflict() {
+	voteConflicts.Inc()
+}
+
+// ObserveIPChange 记录检测到的新 IP，替换该记录之前的 IP 标签
+func ObserveIPChange(record, family, ip string) {
+	currentIP.DeletePartialMatch(prometheus.Labels{"record": record})
+	currentIP.WithLabelValues(record, family, ip).Set(1)
+	lastIPChange.WithLabelValues(record).SetToCurrentTime()
+}
+
+// ObserveUpdate 统计一次记录更新，成功时刷新最后成功时间
+func ObserveUpdate(record string, err error) {
+	updateAttempts.WithLabelValues(record).Inc()
+	if err != nil {
+		updateFailures.WithLabelValues(record).Inc()
+		return
+	}
+	updateSuccesses.WithLabelValues(record).Inc()
+	lastSuccess.WithLabelValues(record).SetToCurrentTime()
+}
+
+// ObserveProviderCall 统计一次服务商 API 调用
+func ObserveProviderCall(provider, op string, d time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	providerDuration.WithLabelValues(provider, op, result).Observe(d.Seconds())
+}
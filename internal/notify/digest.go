@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 摘要中逐条列出的 IP 变化和失败事件数上限
+const digestMaxLines = 50
+
+// DigestNotifier 支持每日摘要的通知方式
+type DigestNotifier interface {
+	SendDigest(ctx context.Context, d Digest) error
+}
+
+// DigestSource 返回 [since, until) 内的全部事件，按时间从旧到新
+type DigestSource func(since, until time.Time) ([]Event, error)
+
+// ParseDigestTime 解析每日摘要的发送时间 HH:MM（本地时区），返回距零点的时长
+func ParseDigestTime(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// nextDigest 返回 now 之后的下一个发送时间
+func nextDigest(now time.Time, at time.Duration) time.Time {
+	hour, minute := int(at/time.Hour), int(at%time.Hour/time.Minute)
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, hour, minute, 0, 0, now.Location())
+	}
+	return next
+}
+
+// Digest 一段时间内全部记录的事件汇总
+type Digest struct {
+	Since  time.Time
+	Until  time.Time
+	Events []Event // 按时间从旧到新
+}
+
+// digestRecord 单条记录的统计
+type digestRecord struct {
+	name           string
+	ipChanges      int
+	updates        int
+	updateFailures int
+	detectFailures int
+	lastIP         string
+	lastError      string
+	lastErrorAt    time.Time
+	recoveredAt    time.Time
+}
+
+func (d Digest) records() []*digestRecord {
+	byName := make(map[string]*digestRecord)
+	var records []*digestRecord
+	for _, e := range d.Events {
+		name := e.Record
+		if e.Type != "" {
+			name += " (" + e.Type + ")"
+		}
+		r := byName[name]
+		if r == nil {
+			r = &digestRecord{name: name}
+			byName[name] = r
+			records = append(records, r)
+		}
+		switch e.Event {
+		case EventIPChanged:
+			r.ipChanges++
+		case EventUpdateSucceeded:
+			r.updates++
+			r.lastIP = e.NewIP
+		case EventUpdateFailed:
+			r.updateFailures++
+		case EventDetectionFailed:
+			r.detectFailures++
+		case EventRecovered:
+			r.recoveredAt = e.Time
+		}
+		if e.Error != "" {
+			r.lastError, r.lastErrorAt = e.Error, e.Time
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].name < records[j].name })
+	return records
+}
+
+// Subject 邮件标题，如 "[OpenDDNS] Daily digest: 2 IP changes, 1 failure"
+func (d Digest) Subject() string {
+	var changes, failures int
+	for _, e := range d.Events {
+		switch {
+		case e.Event == EventIPChanged:
+			changes++
+		case e.Failed():
+			failures++
+		}
+	}
+	if changes == 0 && failures == 0 {
+		return "[OpenDDNS] Daily digest: no changes"
+	}
+	return fmt.Sprintf("[OpenDDNS] Daily digest: %s, %s", plural(changes, "IP change"), plural(failures, "failure"))
+}
+
+// Text 摘要正文：各记录的统计，以及逐条的 IP 变化和失败
+func (d Digest) Text() string {
+	var b strings.Builder
+	const layout = "2006-01-02 15:04 MST"
+	fmt.Fprintf(&b, "OpenDDNS digest for %s to %s\n", d.Since.Format(layout), d.Until.Format(layout))
+
+	records := d.records()
+	if len(records) == 0 {
+		b.WriteString("\nNo events in this period.\n")
+		return b.String()
+	}
+	for _, r := range records {
+		fmt.Fprintf(&b, "\n%s\n", r.name)
+		fmt.Fprintf(&b, "  IP changes: %d\n", r.ipChanges)
+		fmt.Fprintf(&b, "  Updates: %d succeeded, %d failed\n", r.updates, r.updateFailures)
+		fmt.Fprintf(&b, "  Detection failures: %d\n", r.detectFailures)
+		if r.lastIP != "" {
+			fmt.Fprintf(&b, "  Last pushed IP: %s\n", r.lastIP)
+		}
+		if r.lastError != "" {
+			state := "still failing"
+			if r.recoveredAt.After(r.lastErrorAt) {
+				state = "recovered " + r.recoveredAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(&b, "  Last error (%s): %s\n", state, r.lastError)
+		}
+	}
+
+	var lines []string
+	for _, e := range d.Events {
+		if e.Event != EventIPChanged && !e.Failed() {
+			continue
+		}
+		line := e.Time.Format(time.RFC3339) + "  " + e.Event + "  " + e.Record
+		switch {
+		case e.OldIP != "" && e.NewIP != "":
+			line += "  " + e.OldIP + " → " + e.NewIP
+		case e.NewIP != "":
+			line += "  " + e.NewIP
+		}
+		if e.Error != "" {
+			line += "  " + e.Error
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > 0 {
+		b.WriteString("\nIP changes and failures:\n")
+		if len(lines) > digestMaxLines {
+			fmt.Fprintf(&b, "  (%d earlier events omitted)\n", len(lines)-digestMaxLines)
+			lines = lines[len(lines)-digestMaxLines:]
+		}
+		for _, line := range lines {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
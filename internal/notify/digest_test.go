@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDigestTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"08:00", 8 * time.Hour, false},
+		{"00:00", 0, false},
+		{"23:59", 23*time.Hour + 59*time.Minute, false},
+		{"7:30", 7*time.Hour + 30*time.Minute, false},
+		{"24:00", 0, true},
+		{"08:00:00", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDigestTime(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDigestTime(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNextDigest(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	at := 8 * time.Hour
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"before today's time", time.Date(2026, 3, 1, 7, 59, 0, 0, loc), time.Date(2026, 3, 1, 8, 0, 0, 0, loc)},
+		{"exactly at time", time.Date(2026, 3, 1, 8, 0, 0, 0, loc), time.Date(2026, 3, 2, 8, 0, 0, 0, loc)},
+		{"after today's time", time.Date(2026, 3, 1, 20, 0, 0, 0, loc), time.Date(2026, 3, 2, 8, 0, 0, 0, loc)},
+		{"month end", time.Date(2026, 2, 28, 9, 0, 0, 0, loc), time.Date(2026, 3, 1, 8, 0, 0, 0, loc)},
+		{"year end", time.Date(2026, 12, 31, 23, 0, 0, 0, loc), time.Date(2027, 1, 1, 8, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDigest(tt.now, at); !got.Equal(tt.want) {
+				t.Errorf("nextDigest(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, loc)
+	if got, want := nextDigest(now, 8*time.Hour+30*time.Minute), time.Date(2026, 3, 1, 8, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("nextDigest minutes = %v, want %v", got, want)
+	}
+}
+
+func TestDigestSubject(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+		want   string
+	}{
+		{"empty", nil, "[OpenDDNS] Daily digest: no changes"},
+		{"only successes", []string{EventUpdateSucceeded}, "[OpenDDNS] Daily digest: no changes"},
+		{"one of each", []string{EventIPChanged, EventUpdateFailed}, "[OpenDDNS] Daily digest: 1 IP change, 1 failure"},
+		{"plural", []string{EventIPChanged, EventIPChanged, EventDetectionFailed, EventUpdateFailed, EventRecovered},
+			"[OpenDDNS] Daily digest: 2 IP changes, 2 failures"},
+		{"changes only", []string{EventIPChanged}, "[OpenDDNS] Daily digest: 1 IP change, 0 failures"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Digest
+			for _, name := range tt.events {
+				d.Events = append(d.Events, Event{Event: name, Record: "home.example.com"})
+			}
+			if got := d.Subject(); got != tt.want {
+				t.Errorf("Subject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestText(t *testing.T) {
+	base := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	d := Digest{
+		Since: base,
+		Until: base.Add(24 * time.Hour),
+		Events: []Event{
+			{Event: EventIPChanged, Time: at(1), Record: "home.example.com", Type: "A", OldIP: "203.0.113.1", NewIP: "203.0.113.7"},
+			{Event: EventUpdateFailed, Time: at(1), Record: "home.example.com", Type: "A", NewIP: "203.0.113.7", Error: "timeout"},
+			{Event: EventUpdateSucceeded, Time: at(2), Record: "home.example.com", Type: "A", NewIP: "203.0.113.7"},
+			{Event: EventRecovered, Time: at(2), Record: "home.example.com", Type: "A"},
+			{Event: EventDetectionFailed, Time: at(3), Record: "nas.example.com", Type: "AAAA", Error: "no IPv6"},
+		},
+	}
+	text := d.Text()
+	for _, want := range []string{
+		"OpenDDNS digest for 2026-03-01 08:00 UTC to 2026-03-02 08:00 UTC\n",
+		"\nhome.example.com (A)\n  IP changes: 1\n  Updates: 1 succeeded, 1 failed\n  Detection failures: 0\n  Last pushed IP: 203.0.113.7\n" +
+			"  Last error (recovered 2026-03-01T10:00:00Z): timeout\n",
+		"\nnas.example.com (AAAA)\n  IP changes: 0\n  Updates: 0 succeeded, 0 failed\n  Detection failures: 1\n" +
+			"  Last error (still failing): no IPv6\n",
+		"\nIP changes and failures:\n" +
+			"  2026-03-01T09:00:00Z  ip_changed  home.example.com  203.0.113.1 → 203.0.113.7\n" +
+			"  2026-03-01T09:00:00Z  update_failed  home.example.com  203.0.113.7  timeout\n" +
+			"  2026-03-01T11:00:00Z  detection_failed  nas.example.com  no IPv6\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q\ngot:\n%s", want, text)
+		}
+	}
+	// 记录按名称排序
+	if strings.Index(text, "home.example.com (A)") > strings.Index(text, "nas.example.com (AAAA)") {
+		t.Errorf("records not sorted:\n%s", text)
+	}
+
+	empty := Digest{Since: base, Until: base.Add(24 * time.Hour)}
+	if got := empty.Text(); !strings.HasSuffix(got, "\nNo events in this period.\n") {
+		t.Errorf("empty Text() = %q", got)
+	}
+}
+
+func TestDigestTextTruncates(t *testing.T) {
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	var d Digest
+	for i := 0; i < digestMaxLines+5; i++ {
+		d.Events = append(d.Events, Event{Event: EventIPChanged, Time: base.Add(time.Duration(i) * time.Minute), Record: "home.example.com", NewIP: "203.0.113.7"})
+	}
+	text := d.Text()
+	if !strings.Contains(text, "  (5 earlier events omitted)\n") {
+		t.Errorf("missing omitted note:\n%s", text)
+	}
+	if strings.Contains(text, base.Format(time.RFC3339)) {
+		t.Error("oldest event should be omitted")
+	}
+	if !strings.Contains(text, base.Add(time.Duration(digestMaxLines+4)*time.Minute).Format(time.RFC3339)) {
+		t.Error("newest event should be listed")
+	}
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DingTalk 钉钉自定义机器人，secret 非空时使用加签
+type DingTalk struct {
+	url    string // https://oapi.dingtalk.com/robot/send?access_token=...
+	secret string
+}
+
+func NewDingTalk(webhookURL, secret string) *DingTalk {
+	return &DingTalk{url: webhookURL, secret: secret}
+}
+
+func (d *DingTalk) Notify(ctx context.Context, e Event) error {
+	target, err := url.Parse(d.url)
+	if err != nil {
+		return err
+	}
+	if d.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		query := target.Query()
+		query.Set("timestamp", timestamp)
+		query.Set("sign", dingTalkSign(timestamp, d.secret))
+		target.RawQuery = query.Encode()
+	}
+	body, err := postJSON(ctx, target.String(), map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": e.Title(),
+			"text":  markdown(e),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkErrcode(body)
+}
+
+// dingTalkSign 对 "timestamp\nsecret" 以 secret 为密钥做 HMAC-SHA256 后 Base64 编码
+func dingTalkSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
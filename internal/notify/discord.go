@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// embed 颜色
+const (
+	discordGreen = 0x2ea043
+	discordRed   = 0xcf222e
+	discordBlue  = 0x0969da
+)
+
+// embed 的长度限制，超出时返回 400
+const (
+	discordMaxTitle      = 256
+	discordMaxFieldValue = 1024
+)
+
+// Discord 频道 Webhook
+type Discord struct {
+	url string // https://discord.com/api/webhooks/...
+}
+
+func NewDiscord(webhookURL string) *Discord {
+	return &Discord{url: webhookURL}
+}
+
+func (d *Discord) Notify(ctx context.Context, e Event) error {
+	color := discordBlue
+	switch {
+	case e.Failed():
+		color = discordRed
+	case e.Event == EventUpdateSucceeded || e.Event == EventRecovered:
+		color = discordGreen
+	}
+	var fields []map[string]any
+	for _, f := range e.Fields() {
+		if f.Name == "Time" {
+			continue // 使用 embed 自带的时间戳
+		}
+		fields = append(fields, map[string]any{"name": f.Name, "value": truncate(f.Value, discordMaxFieldValue), "inline": f.Name != "Error"})
+	}
+	_, err := postJSON(ctx, d.url, map[string]any{
+		"username": "OpenDDNS",
+		"embeds": []any{map[string]any{
+			"title":     truncate(e.Title(), discordMaxTitle),
+			"color":     color,
+			"fields":    fields,
+			"timestamp": e.Time.Format(time.RFC3339),
+		}},
+	})
+	return err
+}
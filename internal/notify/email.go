@@ -0,0 +1,237 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP 连接加密方式
+const (
+	SecurityStartTLS = "starttls" // 明文连接后升级，通常为 587 端口
+	SecurityTLS      = "tls"      // 隐式 TLS，通常为 465 端口
+	SecurityNone     = "none"     // 不加密，仅用于本机或内网中继
+)
+
+// EmailOptions SMTP 发送设置
+type EmailOptions struct {
+	Host     string
+	Port     int    // 默认按加密方式取 587、465 或 25
+	Security string // 默认 465 端口为 tls，其它为 starttls
+	Username string // 为空时不认证
+	Password string
+	From     string   // 可带显示名，如 "OpenDDNS <ddns@example.com>"
+	To       []string // 一个或多个收件人
+}
+
+// Email 通过 SMTP 发送邮件
+type Email struct {
+	addr     string
+	host     string
+	security string
+	username string
+	password string
+	from     *mail.Address
+	to       []*mail.Address
+	rootCAs  *x509.CertPool // 为空时使用系统证书，测试时替换
+}
+
+// NewEmail 创建邮件通知，校验地址格式并补全端口和加密方式
+func NewEmail(o EmailOptions) (*Email, error) {
+	security := strings.ToLower(o.Security)
+	if security == "" {
+		security = SecurityStartTLS
+		if o.Port == 465 {
+			security = SecurityTLS
+		}
+	}
+	port := o.Port
+	if port == 0 {
+		switch security {
+		case SecurityTLS:
+			port = 465
+		case SecurityNone:
+			port = 25
+		default:
+			port = 587
+		}
+	}
+	from, err := mail.ParseAddress(o.From)
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	if len(o.To) == 0 {
+		return nil, errors.New("to: at least one recipient is required")
+	}
+	to := make([]*mail.Address, 0, len(o.To))
+	for _, addr := range o.To {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("to %q: %w", addr, err)
+		}
+		to = append(to, a)
+	}
+	return &Email{
+		addr:     net.JoinHostPort(o.Host, strconv.Itoa(port)),
+		host:     o.Host,
+		security: security,
+		username: o.Username,
+		password: o.Password,
+		from:     from,
+		to:       to,
+	}, nil
+}
+
+// Notify 每个事件发送一封邮件
+func (m *Email) Notify(ctx context.Context, e Event) error {
+	return m.send(ctx, e.Title(), e.Message())
+}
+
+// SendDigest 发送摘要邮件
+func (m *Email) SendDigest(ctx context.Context, d Digest) error {
+	return m.send(ctx, d.Subject(), d.Text())
+}
+
+func (m *Email) send(ctx context.Context, subject, text string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// 整个会话受 ctx 的超时限制
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConfig := &tls.Config{ServerName: m.host, RootCAs: m.rootCAs}
+	if m.security == SecurityTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if m.security == SecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if m.username != "" {
+		if err := c.Auth(m.auth(c)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := c.Mail(m.from.Address); err != nil {
+		return err
+	}
+	for _, a := range m.to {
+		if err := c.Rcpt(a.Address); err != nil {
+			return fmt.Errorf("rcpt %s: %w", a.Address, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(m.message(subject, text)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// auth 服务器只支持 LOGIN 时（如 Outlook）使用 LOGIN，否则使用 PLAIN
+func (m *Email) auth(c *smtp.Client) smtp.Auth {
+	if ok, mechs := c.Extension("AUTH"); ok {
+		if !strings.Contains(" "+mechs+" ", " PLAIN ") && strings.Contains(" "+mechs+" ", " LOGIN ") {
+			return &loginAuth{host: m.host, username: m.username, password: m.password}
+		}
+	}
+	return smtp.PlainAuth("", m.username, m.password, m.host)
+}
+
+// message 生成 UTF-8 纯文本邮件，正文使用 quoted-printable 编码
+func (m *Email) message(subject, text string) []byte {
+	to := make([]string, len(m.to))
+	for i, a := range m.to {
+		to[i] = a.String()
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: %s\r\n", m.messageID())
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	qp.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n")))
+	qp.Close()
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func (m *Email) messageID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	domain := m.host
+	if at := strings.LastIndex(m.from.Address, "@"); at >= 0 {
+		domain = m.from.Address[at+1:]
+	}
+	return "<" + hex.EncodeToString(buf) + "@" + domain + ">"
+}
+
+// loginAuth SMTP AUTH LOGIN，与 smtp.PlainAuth 一样只在 TLS 或本机连接上发送密码
+type loginAuth struct {
+	host     string
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSuffix(string(fromServer), ":")) {
+	case "username":
+		return []byte(a.username), nil
+	case "password":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,324 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession 桩服务器收到的一封邮件
+type smtpSession struct {
+	tls      bool
+	auth     string // 使用的认证方式及凭据，如 "PLAIN user:pass"
+	from     string
+	rcpt     []string
+	data     string
+	commands []string
+}
+
+// smtpStub 进程内 SMTP 服务器，只实现发信所需的命令
+type smtpStub struct {
+	ln       net.Listener
+	tls      *tls.Config // 非空时支持 STARTTLS
+	implicit bool        // 连接即 TLS
+	mechs    string      // EHLO 中公布的 AUTH 方式
+	sessions chan smtpSession
+}
+
+func newSMTPStub(t *testing.T, cert *tls.Certificate, implicit bool, mechs string) *smtpStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{ln: ln, implicit: implicit, mechs: mechs, sessions: make(chan smtpSession, 1)}
+	if cert != nil {
+		s.tls = &tls.Config{Certificates: []tls.Certificate{*cert}}
+	}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpStub) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStub) handle(conn net.Conn) {
+	defer conn.Close()
+	var sess smtpSession
+	if s.implicit {
+		conn = tls.Server(conn, s.tls)
+		sess.tls = true
+	}
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 stub ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		sess.commands = append(sess.commands, line)
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			ext := []string{"250-stub"}
+			if s.tls != nil && !sess.tls {
+				ext = append(ext, "250-STARTTLS")
+			}
+			if s.mechs != "" {
+				ext = append(ext, "250-AUTH "+s.mechs)
+			}
+			ext = append(ext, "250 8BITMIME")
+			tp.PrintfLine("%s", strings.Join(ext, "\r\n"))
+		case "STARTTLS":
+			tp.PrintfLine("220 go ahead")
+			conn = tls.Server(conn, s.tls)
+			tp = textproto.NewConn(conn)
+			sess.tls = true
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			switch mech {
+			case "PLAIN":
+				raw, _ := base64.StdEncoding.DecodeString(initial)
+				parts := strings.Split(string(raw), "\x00")
+				sess.auth = "PLAIN " + parts[1] + ":" + parts[2]
+			case "LOGIN":
+				tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte("Username:")))
+				user, _ := tp.ReadLine()
+				tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte("Password:")))
+				pass, _ := tp.ReadLine()
+				u, _ := base64.StdEncoding.DecodeString(user)
+				p, _ := base64.StdEncoding.DecodeString(pass)
+				sess.auth = "LOGIN " + string(u) + ":" + string(p)
+			}
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			sess.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			if i := strings.Index(sess.from, ">"); i >= 0 {
+				sess.from = sess.from[:i]
+			}
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			sess.rcpt = append(sess.rcpt, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes() // 行尾已转换为 \n
+			if err != nil {
+				return
+			}
+			sess.data = string(data)
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			s.sessions <- sess
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (s *smtpStub) session(t *testing.T) smtpSession {
+	t.Helper()
+	select {
+	case sess := <-s.sessions:
+		return sess
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+		return smtpSession{}
+	}
+}
+
+// selfSigned 生成 127.0.0.1 的自签名证书，返回证书和信任它的 CertPool
+func selfSigned(t *testing.T) (*tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "stub"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestEmailSend(t *testing.T) {
+	cert, pool := selfSigned(t)
+	tests := []struct {
+		name     string
+		security string
+		mechs    string
+		username string
+		wantTLS  bool
+		wantAuth string
+	}{
+		{"plain without auth", SecurityNone, "", "", false, ""},
+		{"plain with auth on localhost", SecurityNone, "PLAIN LOGIN", "ddns", false, "PLAIN ddns:secret"},
+		{"starttls plain auth", SecurityStartTLS, "PLAIN LOGIN", "ddns", true, "PLAIN ddns:secret"},
+		{"starttls login auth", SecurityStartTLS, "LOGIN", "ddns", true, "LOGIN ddns:secret"},
+		{"implicit tls", SecurityTLS, "PLAIN", "ddns", true, "PLAIN ddns:secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c *tls.Certificate
+			if tt.security != SecurityNone {
+				c = cert
+			}
+			stub := newSMTPStub(t, c, tt.security == SecurityTLS, tt.mechs)
+			m, err := NewEmail(EmailOptions{
+				Host:     "127.0.0.1",
+				Port:     stub.port(),
+				Security: tt.security,
+				Username: tt.username,
+				Password: "secret",
+				From:     "OpenDDNS <ddns@example.com>",
+				To:       []string{"a@example.com", "Ops <b@example.com>"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.rootCAs = pool
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			e := Event{Event: EventUpdateSucceeded, Time: time.Now(), Record: "home.example.com", Provider: "cloudflare", Type: "A", OldIP: "203.0.113.1", NewIP: "203.0.113.7"}
+			if err := m.Notify(ctx, e); err != nil {
+				t.Fatal(err)
+			}
+
+			sess := stub.session(t)
+			if sess.tls != tt.wantTLS {
+				t.Errorf("tls = %v, want %v", sess.tls, tt.wantTLS)
+			}
+			if sess.auth != tt.wantAuth {
+				t.Errorf("auth = %q, want %q", sess.auth, tt.wantAuth)
+			}
+			if sess.from != "ddns@example.com" {
+				t.Errorf("from = %q", sess.from)
+			}
+			if want := []string{"a@example.com", "b@example.com"}; !reflect.DeepEqual(sess.rcpt, want) {
+				t.Errorf("rcpt = %q, want %q", sess.rcpt, want)
+			}
+			if !strings.Contains(sess.data, "To: <a@example.com>, \"Ops\" <b@example.com>\n") {
+				t.Errorf("missing To header:\n%s", sess.data)
+			}
+			header, body, _ := strings.Cut(sess.data, "\n\n")
+			if !strings.Contains(header, "Content-Transfer-Encoding: quoted-printable") {
+				t.Errorf("missing transfer encoding:\n%s", header)
+			}
+			decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(decoded), "203.0.113.7") {
+				t.Errorf("body missing new IP:\n%s", decoded)
+			}
+		})
+	}
+}
+
+func TestEmailStartTLSRequired(t *testing.T) {
+	stub := newSMTPStub(t, nil, false, "PLAIN")
+	m, err := NewEmail(EmailOptions{
+		Host: "127.0.0.1", Port: stub.port(), Security: SecurityStartTLS,
+		Username: "ddns", Password: "secret",
+		From: "ddns@example.com", To: []string{"a@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Notify(context.Background(), Event{Event: EventIPChanged, Record: "home.example.com"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("err = %v, want STARTTLS error", err)
+	}
+}
+
+func TestEmailUntrustedCertificate(t *testing.T) {
+	cert, _ := selfSigned(t)
+	stub := newSMTPStub(t, cert, false, "PLAIN")
+	m, err := NewEmail(EmailOptions{
+		Host: "127.0.0.1", Port: stub.port(),
+		Username: "ddns", Password: "secret",
+		From: "ddns@example.com", To: []string{"a@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Notify(context.Background(), Event{Event: EventIPChanged, Record: "home.example.com"}); err == nil {
+		t.Fatal("expected certificate verification error")
+	}
+}
+
+func TestNewEmailDefaults(t *testing.T) {
+	tests := []struct {
+		port     int
+		security string
+		wantAddr string
+		wantSec  string
+	}{
+		{0, "", "smtp.example.com:587", SecurityStartTLS},
+		{465, "", "smtp.example.com:465", SecurityTLS},
+		{0, "TLS", "smtp.example.com:465", SecurityTLS},
+		{0, "none", "smtp.example.com:25", SecurityNone},
+		{2525, "", "smtp.example.com:2525", SecurityStartTLS},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.port)+"/"+tt.security, func(t *testing.T) {
+			m, err := NewEmail(EmailOptions{Host: "smtp.example.com", Port: tt.port, Security: tt.security, From: "ddns@example.com", To: []string{"a@example.com"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.addr != tt.wantAddr || m.security != tt.wantSec {
+				t.Errorf("got %s %s, want %s %s", m.addr, m.security, tt.wantAddr, tt.wantSec)
+			}
+		})
+	}
+	if _, err := NewEmail(EmailOptions{Host: "smtp.example.com", From: "ddns@example.com"}); err == nil {
+		t.Error("expected error without recipients")
+	}
+	if _, err := NewEmail(EmailOptions{Host: "smtp.example.com", From: "not an address", To: []string{"a@example.com"}}); err == nil {
+		t.Error("expected error for invalid from")
+	}
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Feishu 飞书/Lark 自定义机器人，secret 非空时使用签名校验
+type Feishu struct {
+	url    string // https://open.feishu.cn/open-apis/bot/v2/hook/... 或 open.larksuite.com
+	secret string
+}
+
+func NewFeishu(webhookURL, secret string) *Feishu {
+	return &Feishu{url: webhookURL, secret: secret}
+}
+
+func (f *Feishu) Notify(ctx context.Context, e Event) error {
+	color := "blue"
+	switch {
+	case e.Failed():
+		color = "red"
+	case e.Event == EventUpdateSucceeded || e.Event == EventRecovered:
+		color = "green"
+	}
+	payload := map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"header": map[string]any{
+				"title":    map[string]string{"tag": "plain_text", "content": e.Title()},
+				"template": color,
+			},
+			"elements": []any{
+				map[string]any{
+					"tag":  "div",
+					"text": map[string]string{"tag": "lark_md", "content": larkMarkdown(e)},
+				},
+			},
+		},
+	}
+	if f.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		sign, err := feishuSign(timestamp, f.secret)
+		if err != nil {
+			return err
+		}
+		payload["timestamp"] = timestamp
+		payload["sign"] = sign
+	}
+	body, err := postJSON(ctx, f.url, payload)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("unexpected response: %s", body)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("code %d: %s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// feishuSign 以 "timestamp\nsecret" 为密钥对空消息做 HMAC-SHA256 后 Base64 编码
+func feishuSign(timestamp, secret string) (string, error) {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	if _, err := mac.Write(nil); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// larkMarkdown 卡片正文，每项一行
+func larkMarkdown(e Event) string {
+	var text string
+	for i, f := range e.Fields() {
+		if i > 0 {
+			text += "\n"
+		}
+		text += fmt.Sprintf("**%s**: %s", f.Name, f.Value)
+	}
+	return text
+}
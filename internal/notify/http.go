@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// httpClient 各通知方式共用，超时由 ctx 控制
+var httpClient = &http.Client{}
+
+// doRequest 发送请求并返回响应内容，非 2xx 状态码视为失败并附带响应开头的内容
+func doRequest(req *http.Request) ([]byte, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "OpenDDNS")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet := string(body)
+		if len(snippet) > 256 {
+			snippet = snippet[:256]
+		}
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(snippet))
+	}
+	return body, nil
+}
+
+// postJSON 以 JSON 发送 payload，返回响应内容
+func postJSON(ctx context.Context, url string, payload any) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(req)
+}
+
+// checkErrcode 检查钉钉、企业微信形式的响应 {"errcode":0,"errmsg":"ok"}
+func checkErrcode(body []byte) error {
+	var resp struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("unexpected response: %s", body)
+	}
+	if resp.ErrCode != 0 {
+		return fmt.Errorf("errcode %d: %s", resp.ErrCode, resp.ErrMsg)
+	}
+	return nil
+}
+
+// markdown 生成 Markdown 正文：标题加每项一行
+func markdown(e Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n", e.Title())
+	for _, f := range e.Fields() {
+		fmt.Fprintf(&b, "\n- **%s**: %s", f.Name, f.Value)
+	}
+	return b.String()
+}
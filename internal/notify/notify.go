@@ -0,0 +1,298 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 通知事件类型，与历史记录的事件名相同
+const (
+	EventIPChanged       = "ip_changed"
+	EventUpdateSucceeded = "update_succeeded"
+	EventUpdateFailed    = "update_failed"
+	EventDetectionFailed = "detection_failed"
+	EventRecovered       = "recovered"
+)
+
+// Events 所有可订阅的事件
+var Events = []string{EventIPChanged, EventUpdateSucceeded, EventUpdateFailed, EventDetectionFailed, EventRecovered}
+
+// ValidEvent 判断事件名是否有效
+func ValidEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 未配置时单次发送的超时
+const DefaultTimeout = 10 * time.Second
+
+// 等待发送的事件数上限，超过时丢弃新事件
+const queueSize = 64
+
+var (
+	logDebugFunc func(string, ...interface{})
+	logWarnFunc  func(string, ...interface{})
+)
+
+// SetLogger 设置通知模块使用的日志函数
+func SetLogger(debug, warn func(string, ...interface{})) {
+	logDebugFunc = debug
+	logWarnFunc = warn
+}
+
+func logDebug(format string, a ...interface{}) {
+	if logDebugFunc != nil {
+		logDebugFunc(format, a...)
+	}
+}
+
+func logWarn(format string, a ...interface{}) {
+	if logWarnFunc != nil {
+		logWarnFunc(format, a...)
+	}
+}
+
+// Event 一次通知的内容，字段可在模板中使用，如 {{.Record}}、{{.NewIP}}
+type Event struct {
+	Event    string
+	Time     time.Time
+	Record   string
+	Provider string
+	Type     string
+	OldIP    string
+	NewIP    string
+	Error    string
+	DryRun   bool
+	Lag      time.Duration // 从检测到新 IP 到更新成功的时长
+}
+
+// Title 简短标题，如 "[OpenDDNS] www.example.com: IP changed"
+func (e Event) Title() string {
+	var what string
+	switch e.Event {
+	case EventIPChanged:
+		what = "IP changed"
+	case EventUpdateSucceeded:
+		what = "DNS record updated"
+	case EventUpdateFailed:
+		what = "DNS update failed"
+	case EventDetectionFailed:
+		what = "IP detection failed"
+	case EventRecovered:
+		what = "recovered"
+	default:
+		what = e.Event
+	}
+	if e.DryRun {
+		what += " (dry-run)"
+	}
+	return fmt.Sprintf("[OpenDDNS] %s: %s", e.Record, what)
+}
+
+// Field 消息中的一项内容
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields 按显示顺序返回事件的各项内容，空值省略
+func (e Event) Fields() []Field {
+	record := e.Record
+	if e.Type != "" {
+		record += " (" + e.Type + ")"
+	}
+	fields := []Field{{"Record", record}}
+	if e.Provider != "" {
+		fields = append(fields, Field{"Provider", e.Provider})
+	}
+	switch {
+	case e.OldIP != "" && e.NewIP != "":
+		fields = append(fields, Field{"IP", e.OldIP + " → " + e.NewIP})
+	case e.NewIP != "":
+		fields = append(fields, Field{"IP", e.NewIP})
+	case e.OldIP != "":
+		fields = append(fields, Field{"Last IP", e.OldIP})
+	}
+	if e.Lag > 0 {
+		fields = append(fields, Field{"Lag", e.Lag.Round(time.Second).String()})
+	}
+	if e.Error != "" {
+		fields = append(fields, Field{"Error", e.Error})
+	}
+	return append(fields, Field{"Time", e.Time.Format(time.RFC3339)})
+}
+
+// Message 多行的可读描述
+func (e Event) Message() string {
+	var b strings.Builder
+	b.WriteString(e.Title())
+	for _, f := range e.Fields() {
+		fmt.Fprintf(&b, "\n%s: %s", f.Name, f.Value)
+	}
+	return b.String()
+}
+
+// Failed 是否为失败事件，用于选择消息颜色
+func (e Event) Failed() bool {
+	return e.Event == EventUpdateFailed || e.Event == EventDetectionFailed
+}
+
+// truncate 把 s 截断到最多 max 个字符，截断时以 "…" 结尾。各平台对字段长度有限制，超出时整条消息会被拒绝
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+// Notifier 一种通知方式
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// Target 一个通知目标及其订阅的事件，Events 为空时订阅全部
+type Target struct {
+	Name     string
+	Notifier Notifier
+	Events   []string
+	Timeout  time.Duration
+	Digest   bool          // 每日发送摘要，Notifier 需实现 DigestNotifier
+	DigestAt time.Duration // 摘要发送时间，距本地零点
+}
+
+func (t Target) timeout() time.Duration {
+	if t.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return t.Timeout
+}
+
+func (t Target) wants(event string) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatcher 在后台按顺序发送通知，不阻塞同步流程
+type Dispatcher struct {
+	targets []Target
+	queue   chan Event
+	done    chan struct{}
+	stop    chan struct{} // 关闭时结束摘要定时
+	once    sync.Once
+}
+
+// NewDispatcher 创建并启动发送队列
+func NewDispatcher(targets []Target) *Dispatcher {
+	d := &Dispatcher{
+		targets: targets,
+		queue:   make(chan Event, queueSize),
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Send 将事件放入队列，队列已满时丢弃并记录警告
+func (d *Dispatcher) Send(e Event) {
+	select {
+	case d.queue <- e:
+	default:
+		logWarn("Notification queue full, dropping %s event for %s.", e.Event, e.Record)
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	for e := range d.queue {
+		for _, t := range d.targets {
+			if !t.wants(e.Event) {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), t.timeout())
+			err := t.Notifier.Notify(ctx, e)
+			cancel()
+			if err != nil {
+				logWarn("Notifier %s failed to send %s: %v", t.Name, e.Event, err)
+				continue
+			}
+			logDebug("Notifier %s sent %s.", t.Name, e.Event)
+		}
+	}
+}
+
+// StartDigests 为开启摘要的目标启动每日定时，摘要内容从 source 获取
+func (d *Dispatcher) StartDigests(source DigestSource) {
+	for _, t := range d.targets {
+		if !t.Digest {
+			continue
+		}
+		sender, ok := t.Notifier.(DigestNotifier)
+		if !ok {
+			logWarn("Notifier %s does not support digests.", t.Name)
+			continue
+		}
+		go d.runDigest(t, sender, source)
+	}
+}
+
+func (d *Dispatcher) runDigest(t Target, sender DigestNotifier, source DigestSource) {
+	for {
+		until := nextDigest(time.Now(), t.DigestAt)
+		logDebug("Next digest for notifier %s at %s.", t.Name, until.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(until))
+		select {
+		case <-d.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		since := until.AddDate(0, 0, -1)
+		events, err := source(since, until)
+		if err != nil {
+			logWarn("Notifier %s failed to collect digest events: %v", t.Name, err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), t.timeout())
+		err = sender.SendDigest(ctx, Digest{Since: since, Until: until, Events: events})
+		cancel()
+		if err != nil {
+			logWarn("Notifier %s failed to send digest: %v", t.Name, err)
+			continue
+		}
+		logDebug("Notifier %s sent digest with %d events.", t.Name, len(events))
+	}
+}
+
+// Close 停止接收新事件和摘要定时，并在 wait 内等待队列中的通知发送完；wait 为 0 时不等待
+func (d *Dispatcher) Close(wait time.Duration) {
+	d.once.Do(func() {
+		close(d.queue)
+		close(d.stop)
+	})
+	if wait <= 0 {
+		return
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-d.done:
+	case <-timer.C:
+		logWarn("Gave up waiting for pending notifications after %s.", wait)
+	}
+}
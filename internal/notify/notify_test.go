@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"longer than ten", 10, "longer th…"},
+		{"更新失败：连接超时", 5, "更新失败…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.max); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
+
+// capture 启动记录请求体的 Webhook 服务
+func capture(t *testing.T, reply string) (*httptest.Server, *map[string]any) {
+	t.Helper()
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &payload
+}
+
+// longEvent 错误信息和记录名都超出平台限制的失败事件
+func longEvent() Event {
+	return Event{
+		Event:  EventUpdateFailed,
+		Time:   time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+		Record: strings.Repeat("a", 300) + ".example.com",
+		Error:  strings.Repeat("provider error ", 400),
+	}
+}
+
+func TestDiscordTruncatesEmbed(t *testing.T) {
+	srv, payload := capture(t, "")
+	if err := NewDiscord(srv.URL).Notify(context.Background(), longEvent()); err != nil {
+		t.Fatal(err)
+	}
+	embed := (*payload)["embeds"].([]any)[0].(map[string]any)
+	if n := utf8.RuneCountInString(embed["title"].(string)); n != discordMaxTitle {
+		t.Errorf("title length = %d, want %d", n, discordMaxTitle)
+	}
+	for _, f := range embed["fields"].([]any) {
+		value := f.(map[string]any)["value"].(string)
+		if n := utf8.RuneCountInString(value); n > discordMaxFieldValue {
+			t.Errorf("field %v length = %d, want at most %d", f.(map[string]any)["name"], n, discordMaxFieldValue)
+		}
+	}
+}
+
+func TestSlackTruncatesBlocks(t *testing.T) {
+	srv, payload := capture(t, "ok")
+	if err := NewSlack(srv.URL).Notify(context.Background(), longEvent()); err != nil {
+		t.Fatal(err)
+	}
+	blocks := (*payload)["blocks"].([]any)
+	header := blocks[0].(map[string]any)["text"].(map[string]any)["text"].(string)
+	section := blocks[1].(map[string]any)["text"].(map[string]any)["text"].(string)
+	if n := utf8.RuneCountInString(header); n != slackMaxHeader {
+		t.Errorf("header length = %d, want %d", n, slackMaxHeader)
+	}
+	if n := utf8.RuneCountInString(section); n != slackMaxSection || !strings.HasSuffix(section, "…") {
+		t.Errorf("section length = %d, want %d ending in …", n, slackMaxSection)
+	}
+}
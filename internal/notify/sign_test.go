@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// 期望值按钉钉、飞书文档中的算法独立计算
+const testSignSecret = "SECexample0123456789"
+
+func TestDingTalkSign(t *testing.T) {
+	if got, want := dingTalkSign("1700000000000", testSignSecret), "figG6qqCA/lRh0hsGCW2zNtNV5znkAFut5BI0zKhqnQ="; got != want {
+		t.Errorf("dingTalkSign() = %q, want %q", got, want)
+	}
+}
+
+func TestFeishuSign(t *testing.T) {
+	got, err := feishuSign("1700000000", testSignSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "S7TuSij45SE3ul/eGkJ5Bwcxf8ZNyPIQzqpjNU85VYk="; got != want {
+		t.Errorf("feishuSign() = %q, want %q", got, want)
+	}
+}
+
+func TestDingTalkSignedRequest(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		io.WriteString(w, `{"errcode":0,"errmsg":"ok"}`)
+	}))
+	defer srv.Close()
+
+	d := NewDingTalk(srv.URL+"/robot/send?access_token=abc", testSignSecret)
+	if err := d.Notify(context.Background(), Event{Event: EventIPChanged, Time: time.Now(), Record: "home.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("access_token") != "abc" {
+		t.Errorf("access_token lost: %v", query)
+	}
+	ts := query.Get("timestamp")
+	if ts == "" || query.Get("sign") != dingTalkSign(ts, testSignSecret) {
+		t.Errorf("sign %q does not match timestamp %q", query.Get("sign"), ts)
+	}
+}
+
+func TestFeishuSignedRequest(t *testing.T) {
+	var payload struct {
+		Timestamp string `json:"timestamp"`
+		Sign      string `json:"sign"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		io.WriteString(w, `{"code":0,"msg":"success"}`)
+	}))
+	defer srv.Close()
+
+	f := NewFeishu(srv.URL, testSignSecret)
+	if err := f.Notify(context.Background(), Event{Event: EventIPChanged, Time: time.Now(), Record: "home.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := feishuSign(payload.Timestamp, testSignSecret)
+	if payload.Timestamp == "" || payload.Sign != want {
+		t.Errorf("sign %q does not match timestamp %q", payload.Sign, payload.Timestamp)
+	}
+}
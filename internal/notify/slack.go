@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Block Kit 的长度限制，超出时返回 invalid_blocks
+const (
+	slackMaxHeader  = 150
+	slackMaxSection = 3000
+)
+
+// Slack Incoming Webhook
+type Slack struct {
+	url string // https://hooks.slack.com/services/...
+}
+
+func NewSlack(webhookURL string) *Slack {
+	return &Slack{url: webhookURL}
+}
+
+func (s *Slack) Notify(ctx context.Context, e Event) error {
+	var lines []string
+	for _, f := range e.Fields() {
+		lines = append(lines, fmt.Sprintf("*%s*: %s", f.Name, slackEscape(f.Value)))
+	}
+	_, err := postJSON(ctx, s.url, map[string]any{
+		// text 用于通知预览，blocks 为正文
+		"text": slackEscape(e.Title()),
+		"blocks": []any{
+			map[string]any{
+				"type": "header",
+				"text": map[string]string{"type": "plain_text", "text": truncate(e.Title(), slackMaxHeader)},
+			},
+			map[string]any{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": truncate(strings.Join(lines, "\n"), slackMaxSection)},
+			},
+		},
+	})
+	return err
+}
+
+// slackEscape 转义 mrkdwn 中的控制字符 & < >
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
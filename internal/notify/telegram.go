@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Telegram 默认 Bot API 地址
+const telegramAPI = "https://api.telegram.org"
+
+// Telegram 通过 Bot API 发送消息
+type Telegram struct {
+	api      string
+	botToken string
+	chatID   string
+}
+
+// NewTelegram apiURL 为空时使用官方地址，可指定自建 Bot API 服务或反向代理
+func NewTelegram(apiURL, botToken, chatID string) *Telegram {
+	if apiURL == "" {
+		apiURL = telegramAPI
+	}
+	return &Telegram{api: strings.TrimRight(apiURL, "/"), botToken: botToken, chatID: chatID}
+}
+
+func (t *Telegram) Notify(ctx context.Context, e Event) error {
+	body, err := postJSON(ctx, t.api+"/bot"+t.botToken+"/sendMessage", map[string]any{
+		"chat_id":                  t.chatID,
+		"text":                     e.Message(),
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("unexpected response: %s", body)
+	}
+	if !resp.OK {
+		return fmt.Errorf("telegram: %s", resp.Description)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// 未配置 body 时发送的 JSON
+const defaultWebhookBody = `{"event":{{json .Event}},"record":{{json .Record}},"type":{{json .Type}},` +
+	`"provider":{{json .Provider}},"old_ip":{{json .OldIP}},"new_ip":{{json .NewIP}},"error":{{json .Error}},` +
+	`"dry_run":{{.DryRun}},"time":{{json .Time}},"title":{{json .Title}},"message":{{json .Message}}}`
+
+// templateFuncs 模板中可用的函数，json 将值编码为 JSON（字符串会带引号并转义）
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// ParseTemplate 解析通知模板，用于校验配置
+func ParseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// Webhook 向任意 URL 发送模板渲染的请求
+type Webhook struct {
+	url     string
+	method  string
+	headers map[string]string
+	body    *template.Template
+}
+
+// NewWebhook 创建 webhook 通知，method 默认 POST，body 为空时发送默认 JSON
+func NewWebhook(url, method string, headers map[string]string, body string) (*Webhook, error) {
+	if method == "" {
+		method = http.MethodPost
+	}
+	if body == "" {
+		body = defaultWebhookBody
+	}
+	tmpl, err := ParseTemplate("body", body)
+	if err != nil {
+		return nil, err
+	}
+	return &Webhook{
+		url:     url,
+		method:  strings.ToUpper(method),
+		headers: headers,
+		body:    tmpl,
+	}, nil
+}
+
+func (w *Webhook) Notify(ctx context.Context, e Event) error {
+	var body bytes.Buffer
+	if err := w.body.Execute(&body, e); err != nil {
+		return fmt.Errorf("render body: %w", err)
+	}
+	var reader io.Reader
+	if w.method != http.MethodGet && w.method != http.MethodHead {
+		reader = &body
+	}
+	req, err := http.NewRequestWithContext(ctx, w.method, w.url, reader)
+	if err != nil {
+		return err
+	}
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	_, err = doRequest(req)
+	return err
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// webhookRequest 测试服务收到的请求
+type webhookRequest struct {
+	method string
+	header http.Header
+	body   string
+}
+
+func TestWebhook(t *testing.T) {
+	e := Event{
+		Event:    EventUpdateFailed,
+		Time:     time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+		Record:   "home.example.com",
+		Provider: "cloudflare",
+		Type:     "A",
+		OldIP:    "198.51.100.1",
+		NewIP:    "203.0.113.7",
+		Error:    `timeout "after" 10s`,
+	}
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		body     string
+		want     webhookRequest
+		wantJSON map[string]any // 为空时按 want.body 比较原文
+	}{
+		{
+			name: "default body",
+			want: webhookRequest{method: "POST", header: http.Header{"Content-Type": {"application/json"}}},
+			wantJSON: map[string]any{
+				"event": "update_failed", "record": "home.example.com", "type": "A", "provider": "cloudflare",
+				"old_ip": "198.51.100.1", "new_ip": "203.0.113.7", "error": `timeout "after" 10s`, "dry_run": false,
+				"time": "2026-03-01T08:00:00Z", "title": "[OpenDDNS] home.example.com: DNS update failed",
+				"message": "[OpenDDNS] home.example.com: DNS update failed\nRecord: home.example.com (A)\nProvider: cloudflare\n" +
+					"IP: 198.51.100.1 → 203.0.113.7\nError: timeout \"after\" 10s\nTime: 2026-03-01T08:00:00Z",
+			},
+		},
+		{
+			name:    "custom body, method and headers",
+			method:  "put",
+			headers: map[string]string{"Authorization": "Bearer abc", "Content-Type": "text/plain", "X-Event": "ddns"},
+			body:    `{{.Record}} {{.OldIP}} -> {{.NewIP}} err={{json .Error}} ${NOT_EXPANDED}`,
+			want: webhookRequest{
+				method: "PUT",
+				header: http.Header{"Authorization": {"Bearer abc"}, "Content-Type": {"text/plain"}, "X-Event": {"ddns"}},
+				body:   `home.example.com 198.51.100.1 -> 203.0.113.7 err="timeout \"after\" 10s" ${NOT_EXPANDED}`,
+			},
+		},
+		{
+			name:    "get sends no body",
+			method:  "GET",
+			headers: map[string]string{"X-Record": "home"},
+			body:    "ignored {{.Record}}",
+			want:    webhookRequest{method: "GET", header: http.Header{"X-Record": {"home"}, "Content-Type": nil}, body: ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got webhookRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				got = webhookRequest{method: r.Method, header: r.Header, body: string(data)}
+			}))
+			defer srv.Close()
+
+			w, err := NewWebhook(srv.URL, tt.method, tt.headers, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Notify(context.Background(), e); err != nil {
+				t.Fatal(err)
+			}
+			if got.method != tt.want.method {
+				t.Errorf("method = %s, want %s", got.method, tt.want.method)
+			}
+			for k, v := range tt.want.header {
+				if strings.Join(got.header.Values(k), ",") != strings.Join(v, ",") {
+					t.Errorf("header %s = %q, want %q", k, got.header.Values(k), v)
+				}
+			}
+			if tt.wantJSON == nil {
+				if got.body != tt.want.body {
+					t.Errorf("body:\n got %s\nwant %s", got.body, tt.want.body)
+				}
+				return
+			}
+			var body map[string]any
+			if err := json.Unmarshal([]byte(got.body), &body); err != nil {
+				t.Fatalf("default body is not JSON: %v\n%s", err, got.body)
+			}
+			for k, v := range tt.wantJSON {
+				if body[k] != v {
+					t.Errorf("body[%q] = %#v, want %#v", k, body[k], v)
+				}
+			}
+			if len(body) != len(tt.wantJSON) {
+				t.Errorf("body has %d fields, want %d: %s", len(body), len(tt.wantJSON), got.body)
+			}
+		})
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	if _, err := NewWebhook("http://127.0.0.1", "", nil, "{{.Record"); err == nil {
+		t.Error("NewWebhook accepted an invalid template")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	w, err := NewWebhook(srv.URL, "", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Notify(context.Background(), Event{Event: EventIPChanged}); err == nil || err.Error() != "HTTP 403: bad token" {
+		t.Errorf("Notify error = %v, want HTTP 403: bad token", err)
+	}
+
+	// 模板引用不存在的字段时在执行阶段报错
+	w, err = NewWebhook(srv.URL, "", nil, "{{.Missing}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Notify(context.Background(), Event{}); err == nil || !strings.HasPrefix(err.Error(), "render body:") {
+		t.Errorf("Notify error = %v, want render body error", err)
+	}
+}
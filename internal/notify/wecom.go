@@ -0,0 +1,39 @@
+package notify
+
+import "context"
+
+// WeCom 企业微信群机器人
+type WeCom struct {
+	url string // https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=...
+}
+
+func NewWeCom(webhookURL string) *WeCom {
+	return &WeCom{url: webhookURL}
+}
+
+func (w *WeCom) Notify(ctx context.Context, e Event) error {
+	body, err := postJSON(ctx, w.url, map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": wecomMarkdown(e)},
+	})
+	if err != nil {
+		return err
+	}
+	return checkErrcode(body)
+}
+
+// wecomMarkdown 企业微信 Markdown 支持 <font color> 标注颜色
+func wecomMarkdown(e Event) string {
+	color := "info"
+	switch {
+	case e.Failed():
+		color = "warning"
+	case e.Event == EventIPChanged:
+		color = "comment"
+	}
+	text := "### <font color=\"" + color + "\">" + e.Title() + "</font>\n"
+	for _, f := range e.Fields() {
+		text += "\n> **" + f.Name + "**: " + f.Value
+	}
+	return text
+}
@@ -1,7 +1,10 @@
 package provider
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	alidns "github.com/alibabacloud-go/alidns-20150109/v4/client"
 	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
@@ -14,10 +17,34 @@ type Aliyun struct {
 	Domain          string
 	Subdomain       string
 	Endpoint        string // 可选
+	Ownership       Ownership
+	Options         RecordOptions
+
+	client aliyunAPI // 测试时替换，为空时按凭证创建
+}
+
+// aliyunAPI 用到的云解析接口，由 *alidns.Client 实现
+type aliyunAPI interface {
+	DescribeDomains(*alidns.DescribeDomainsRequest) (*alidns.DescribeDomainsResponse, error)
+	DescribeDomainRecords(*alidns.DescribeDomainRecordsRequest) (*alidns.DescribeDomainRecordsResponse, error)
+	DescribeSubDomainRecords(*alidns.DescribeSubDomainRecordsRequest) (*alidns.DescribeSubDomainRecordsResponse, error)
+	AddDomainRecord(*alidns.AddDomainRecordRequest) (*alidns.AddDomainRecordResponse, error)
+	UpdateDomainRecord(*alidns.UpdateDomainRecordRequest) (*alidns.UpdateDomainRecordResponse, error)
+	UpdateDomainRecordRemark(*alidns.UpdateDomainRecordRemarkRequest) (*alidns.UpdateDomainRecordRemarkResponse, error)
+	DeleteDomainRecord(*alidns.DeleteDomainRecordRequest) (*alidns.DeleteDomainRecordResponse, error)
 }
 
-func (a *Aliyun) UpdateRecord(ip string, recordType string) error {
-	// 构建 OpenAPI Client
+// 新建记录后写入归属标记的重试次数和间隔
+var (
+	remarkAttempts   = 3
+	remarkRetryDelay = 2 * time.Second
+)
+
+// newClient 构建绑定 ctx 的 OpenAPI Client
+func (a *Aliyun) newClient(ctx context.Context) (aliyunClient, error) {
+	if a.client != nil {
+		return aliyunClient{ctx: ctx, api: a.client}, nil
+	}
 	cfg := &openapi.Config{
 		AccessKeyId:     tea.String(a.AccessKeyID),
 		AccessKeySecret: tea.String(a.AccessKeySecret),
@@ -27,64 +54,434 @@ func (a *Aliyun) UpdateRecord(ip string, recordType string) error {
 	}
 	client, err := alidns.NewClient(cfg)
 	if err != nil {
-		return err
+		return aliyunClient{}, err
+	}
+	return aliyunClient{ctx: ctx, api: client}, nil
+}
+
+// aliyunClient 为 SDK 调用加上 ctx：SDK 本身不支持 context，ctx 取消后不再发出新请求，
+// 也不再等待进行中的请求返回
+type aliyunClient struct {
+	ctx context.Context
+	api aliyunAPI
+}
+
+// detached 返回不随 ctx 取消的客户端，用于必须完成的清理
+func (c aliyunClient) detached() aliyunClient {
+	return aliyunClient{ctx: context.WithoutCancel(c.ctx), api: c.api}
+}
+
+func aliyunCall[T any](ctx context.Context, call func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := call()
+		done <- result{value, err}
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+func (c aliyunClient) DescribeDomains(req *alidns.DescribeDomainsRequest) (*alidns.DescribeDomainsResponse, error) {
+	return aliyunCall(c.ctx, func() (*alidns.DescribeDomainsResponse, error) { return c.api.DescribeDomains(req) })
+}
+
+func (c aliyunClient) DescribeDomainRecords(req *alidns.DescribeDomainRecordsRequest) (*alidns.DescribeDomainRecordsResponse, error) {
+	return aliyunCall(c.ctx, func() (*alidns.DescribeDomainRecordsResponse, error) { return c.api.DescribeDomainRecords(req) })
+}
+
+func (c aliyunClient) DescribeSubDomainRecords(req *alidns.DescribeSubDomainRecordsRequest) (*alidns.DescribeSubDomainRecordsResponse, error) {
+	return aliyunCall(c.ctx, func() (*alidns.DescribeSubDomainRecordsResponse, error) { return c.api.DescribeSubDomainRecords(req) })
+}
+
+func (c aliyunClient) AddDomainRecord(req *alidns.AddDomainRecordRequest) (*alidns.AddDomainRecordResponse, error) {
+	return aliyunCall(c.ctx, func() (*alidns.AddDomainRecordResponse, error) { return c.api.AddDomainRecord(req) })
+}
+
+func (c aliyunClient) UpdateDomainRecord(req *alidns.UpdateDomainRecordRequest) (*alidns.UpdateDomainRecordResponse, error) {
+	return aliyunCall(c.ctx, func() (*alidns.UpdateDomainRecordResponse, error) { return c.api.UpdateDomainRecord(req) })
+}
+
+func (c aliyunClient) UpdateDomainRecordRemark(req *alidns.UpdateDomainRecordRemarkRequest) (*alidns.UpdateDomainRecordRemarkResponse, error) {
+	return aliyunCall(c.ctx, func() (*alidns.UpdateDomainRecordRemarkResponse, error) { return c.api.UpdateDomainRecordRemark(req) })
+}
+
+func (c aliyunClient) DeleteDomainRecord(req *alidns.DeleteDomainRecordRequest) (*alidns.DeleteDomainRecordResponse, error) {
+	return aliyunCall(c.ctx, func() (*alidns.DeleteDomainRecordResponse, error) { return c.api.DeleteDomainRecord(req) })
+}
+
+func (a *Aliyun) UpdateRecord(ctx context.Context, ip string, recordType string) ([]Change, error) {
+	return a.reconcile(ctx, ip, recordType, false)
+}
+
+// PlanRecord 计算 UpdateRecord 将执行的变更，不修改任何记录
+func (a *Aliyun) PlanRecord(ctx context.Context, ip string, recordType string) ([]Change, error) {
+	return a.reconcile(ctx, ip, recordType, true)
+}
+
+// DeleteRecord 删除该子域名下指定类型的记录，跳过没有归属标记的记录
+func (a *Aliyun) DeleteRecord(ctx context.Context, recordType string) ([]Change, error) {
+	return a.remove(ctx, recordType, false)
+}
+
+// PlanDelete 计算 DeleteRecord 将执行的变更，不修改任何记录
+func (a *Aliyun) PlanDelete(ctx context.Context, recordType string) ([]Change, error) {
+	return a.remove(ctx, recordType, true)
+}
+
+// ListZones 分页列出账号下的云解析域名
+func (a *Aliyun) ListZones(ctx context.Context) ([]Zone, error) {
+	client, err := a.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result []Zone
+	for page := int64(1); ; page++ {
+		resp, err := client.DescribeDomains(&alidns.DescribeDomainsRequest{
+			PageNumber: tea.Int64(page),
+			PageSize:   tea.Int64(100),
+		})
+		if err != nil {
+			logError("Aliyun DescribeDomains error: %v", err)
+			return nil, err
+		}
+		for _, d := range resp.Body.Domains.Domain {
+			result = append(result, Zone{ID: tea.StringValue(d.DomainId), Name: tea.StringValue(d.DomainName)})
+		}
+		if int64(len(result)) >= tea.Int64Value(resp.Body.TotalCount) || len(resp.Body.Domains.Domain) == 0 {
+			return result, nil
+		}
+	}
+}
+
+// ListRecords 查询记录，subdomain 为空时返回整个域名的记录
+func (a *Aliyun) ListRecords(ctx context.Context, subdomain string, recordType string) ([]Record, error) {
+	client, err := a.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result []Record
+	add := func(id, rr, typ, value, line, remark *string, ttl *int64) {
+		name := a.Domain
+		if rr := tea.StringValue(rr); rr != "@" {
+			name = rr + "." + a.Domain
+		}
+		result = append(result, Record{
+			ID:      tea.StringValue(id),
+			Name:    name,
+			Type:    tea.StringValue(typ),
+			Content: tea.StringValue(value),
+			TTL:     int(tea.Int64Value(ttl)),
+			Line:    tea.StringValue(line),
+			Comment: tea.StringValue(remark),
+			Owned:   a.Ownership.owns(tea.StringValue(remark)),
+		})
+	}
+	if subdomain != "" {
+		req := &alidns.DescribeSubDomainRecordsRequest{
+			SubDomain: tea.String(fmt.Sprintf("%s.%s", subdomain, a.Domain)),
+			PageSize:  tea.Int64(500),
+		}
+		if recordType != "" {
+			req.Type = tea.String(recordType)
+		}
+		resp, err := client.DescribeSubDomainRecords(req)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Body.DomainRecords.Record {
+			add(r.RecordId, r.RR, r.Type, r.Value, r.Line, r.Remark, r.TTL)
+		}
+		return result, nil
+	}
+	// 整个域名分页查询
+	for page := int64(1); ; page++ {
+		req := &alidns.DescribeDomainRecordsRequest{
+			DomainName: tea.String(a.Domain),
+			PageNumber: tea.Int64(page),
+			PageSize:   tea.Int64(500),
+		}
+		if recordType != "" {
+			req.Type = tea.String(recordType)
+		}
+		resp, err := client.DescribeDomainRecords(req)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Body.DomainRecords.Record {
+			add(r.RecordId, r.RR, r.Type, r.Value, r.Line, r.Remark, r.TTL)
+		}
+		if int64(len(result)) >= tea.Int64Value(resp.Body.TotalCount) || len(resp.Body.DomainRecords.Record) == 0 {
+			return result, nil
+		}
 	}
+}
+
+// listRecords 查询该子域名下指定类型的记录
+func (a *Aliyun) listRecords(client aliyunAPI, recordType string) ([]*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord, error) {
 	fqdn := fmt.Sprintf("%s.%s", a.Subdomain, a.Domain)
-	// 查询记录
 	descReq := &alidns.DescribeSubDomainRecordsRequest{
 		SubDomain: tea.String(fqdn),
 		Type:      tea.String(recordType),
 	}
 	descResp, err := client.DescribeSubDomainRecords(descReq)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var matched []*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord
+	for _, record := range descResp.Body.DomainRecords.Record {
+		if *record.RR == a.Subdomain {
+			matched = append(matched, record)
+		}
+	}
+	return matched, nil
+}
+
+// reconcile 使记录指向 ip，dryRun 时只返回变更而不调用修改接口
+func (a *Aliyun) reconcile(ctx context.Context, ip string, recordType string, dryRun bool) ([]Change, error) {
+	client, err := a.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fqdn := fmt.Sprintf("%s.%s", a.Subdomain, a.Domain)
+	// 查询记录
+	records, err := a.listRecords(client, recordType)
+	if err != nil {
+		return nil, err
 	}
-	records := descResp.Body.DomainRecords.Record
 	var toUpdate *alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord
+	var skipped int
 	for _, record := range records {
-		if *record.RR == a.Subdomain && *record.Value == ip {
-			if logInfo != nil {
+		if !a.Ownership.canModify(tea.StringValue(record.Remark)) {
+			logWarn("Aliyun: skip record without ownership marker: %s (%s)", fqdn, tea.StringValue(record.RecordId))
+			skipped++
+			continue
+		}
+		if *record.Value == ip {
+			remark := tea.StringValue(record.Remark)
+			if a.inSync(record) && a.Options.note(a.Ownership, remark) == remark {
 				logInfo("Aliyun: record already up-to-date: %s => %s", fqdn, ip)
+				return nil, nil
 			}
-			return nil // 完全一致，无需操作
-		}
-		if *record.RR == a.Subdomain {
+			// IP 一致但 TTL/线路/优先级或备注需要调整
 			toUpdate = record
+			break
 		}
+		toUpdate = record
 	}
 	if toUpdate != nil {
 		// 有同RR，更新
-		updateReq := &alidns.UpdateDomainRecordRequest{
-			RecordId: tea.String(*toUpdate.RecordId),
-			RR:       tea.String(a.Subdomain),
-			Type:     tea.String(recordType),
-			Value:    tea.String(ip),
-		}
-		_, err = client.UpdateDomainRecord(updateReq)
-		if err == nil && logInfo != nil {
+		req := a.updateRequest(toUpdate, ip)
+		remark := tea.StringValue(toUpdate.Remark)
+		changes := []Change{{
+			Action:   ActionUpdate,
+			Name:     fqdn,
+			Type:     recordType,
+			RecordID: *toUpdate.RecordId,
+			Old:      *toUpdate.Value,
+			New:      ip,
+			OldTTL:   int(tea.Int64Value(toUpdate.TTL)),
+			TTL:      int(tea.Int64Value(req.TTL)),
+			Comment:  a.Options.note(a.Ownership, remark),
+		}}
+		if dryRun {
+			return changes, nil
+		}
+		if !a.inSync(toUpdate) || *toUpdate.Value != ip {
+			_, err = client.UpdateDomainRecord(req)
+			if err != nil {
+				return changes, err
+			}
 			logInfo("Aliyun: record updated: %s => %s", fqdn, ip)
 		}
-		return err
+		return changes, a.setRemark(client, *toUpdate.RecordId, remark, false)
 	}
-	// 没有同RR，自动添加
-	if logWarn != nil {
-		logWarn("Aliyun: record not found for %s, will try to add.", fqdn)
+	if skipped > 0 {
+		logError("Aliyun: refusing to modify %d unowned record(s) for %s", skipped, fqdn)
+		return nil, fmt.Errorf("%s: %w", fqdn, ErrNotOwned)
 	}
+	// 没有同RR，自动添加
 	addReq := &alidns.AddDomainRecordRequest{
 		DomainName: tea.String(a.Domain),
 		RR:         tea.String(a.Subdomain),
 		Type:       tea.String(recordType),
 		Value:      tea.String(ip),
 	}
-	_, err = client.AddDomainRecord(addReq)
+	if a.Options.TTL != 0 {
+		addReq.TTL = tea.Int64(int64(a.Options.TTL))
+	}
+	if a.Options.Line != "" {
+		addReq.Line = tea.String(a.Options.Line)
+	}
+	if a.Options.Priority != 0 {
+		addReq.Priority = tea.Int64(int64(a.Options.Priority))
+	}
+	changes := []Change{{
+		Action:  ActionCreate,
+		Name:    fqdn,
+		Type:    recordType,
+		New:     ip,
+		TTL:     a.Options.TTL,
+		Comment: a.Ownership.tagged(a.Options.Comment),
+	}}
+	if dryRun {
+		return changes, nil
+	}
+	logWarn("Aliyun: record not found for %s, will try to add.", fqdn)
+	addResp, err := client.AddDomainRecord(addReq)
+	if err != nil {
+		logError("Aliyun: add record failed for %s: %v", fqdn, err)
+		return changes, fmt.Errorf("add record failed: %v", err)
+	}
+	logInfo("Aliyun: record created: %s => %s", fqdn, ip)
+	changes[0].RecordID = tea.StringValue(addResp.Body.RecordId)
+	if err := a.tagCreated(client, changes[0].RecordID); err != nil {
+		if !a.Ownership.enabled() {
+			// 记录已创建，只是备注没写上，仍返回创建的变更
+			return changes, err
+		}
+		return nil, err
+	}
+	return changes, nil
+}
+
+// tagCreated 为新建的记录写入备注与归属标记，重试仍失败时删除该记录，
+// 否则未标记的记录此后会一直被当作他人的记录拒绝修改
+func (a *Aliyun) tagCreated(client aliyunClient, recordID string) error {
+	var err error
+	for attempt := 1; attempt <= remarkAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(remarkRetryDelay):
+			case <-client.ctx.Done():
+			}
+		}
+		if err = a.setRemark(client, recordID, "", true); err == nil {
+			return nil
+		}
+		if client.ctx.Err() != nil {
+			break
+		}
+	}
+	if !a.Ownership.enabled() {
+		// 只是备注没写上，记录仍可正常管理
+		return err
+	}
+	// 即使本轮已被取消也要删除，否则记录会一直处于未标记状态
+	_, delErr := client.detached().DeleteDomainRecord(&alidns.DeleteDomainRecordRequest{RecordId: tea.String(recordID)})
+	if delErr != nil {
+		logError("Aliyun: failed to remove untagged record %s, tag or delete it manually: %v", recordID, delErr)
+		return errors.Join(err, fmt.Errorf("remove untagged record %s failed: %v", recordID, delErr))
+	}
+	logWarn("Aliyun: removed record %s because the ownership marker could not be set, will retry next round.", recordID)
+	return fmt.Errorf("%w; created record %s was removed", err, recordID)
+}
+
+// remove 删除指定类型的记录，dryRun 时只返回变更而不调用修改接口
+func (a *Aliyun) remove(ctx context.Context, recordType string, dryRun bool) ([]Change, error) {
+	client, err := a.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fqdn := fmt.Sprintf("%s.%s", a.Subdomain, a.Domain)
+	records, err := a.listRecords(client, recordType)
 	if err != nil {
-		if logError != nil {
-			logError("Aliyun: add record failed for %s: %v", fqdn, err)
+		return nil, err
+	}
+	var changes []Change
+	var skipped int
+	for _, record := range records {
+		if !a.Ownership.canModify(tea.StringValue(record.Remark)) {
+			logWarn("Aliyun: skip deleting record without ownership marker: %s (%s)", fqdn, tea.StringValue(record.RecordId))
+			skipped++
+			continue
+		}
+		changes = append(changes, Change{
+			Action:   ActionDelete,
+			Name:     fqdn,
+			Type:     recordType,
+			RecordID: tea.StringValue(record.RecordId),
+			Old:      tea.StringValue(record.Value),
+			OldTTL:   int(tea.Int64Value(record.TTL)),
+			Comment:  tea.StringValue(record.Remark),
+		})
+		if dryRun {
+			continue
 		}
-		return fmt.Errorf("add record failed: %v", err)
+		_, err := client.DeleteDomainRecord(&alidns.DeleteDomainRecordRequest{RecordId: record.RecordId})
+		if err != nil {
+			logError("Aliyun: delete record failed for %s: %v", fqdn, err)
+			return changes, fmt.Errorf("delete record failed: %v", err)
+		}
+		logInfo("Aliyun: record deleted: %s %s %s", fqdn, recordType, tea.StringValue(record.Value))
+	}
+	if skipped > 0 {
+		return changes, fmt.Errorf("%s: %w", fqdn, ErrNotOwned)
+	}
+	return changes, nil
+}
+
+// updateRequest 根据现有记录和配置生成更新请求，未配置的属性保持原值
+func (a *Aliyun) updateRequest(record *alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord, value string) *alidns.UpdateDomainRecordRequest {
+	req := &alidns.UpdateDomainRecordRequest{
+		RecordId: tea.String(*record.RecordId),
+		RR:       tea.String(a.Subdomain),
+		Type:     record.Type,
+		Value:    tea.String(value),
+		TTL:      record.TTL,
+		Line:     record.Line,
+		Priority: record.Priority,
+	}
+	if !a.Options.PreserveExisting {
+		if a.Options.TTL != 0 {
+			req.TTL = tea.Int64(int64(a.Options.TTL))
+		}
+		if a.Options.Line != "" {
+			req.Line = tea.String(a.Options.Line)
+		}
+		if a.Options.Priority != 0 {
+			req.Priority = tea.Int64(int64(a.Options.Priority))
+		}
+	}
+	return req
+}
+
+// inSync 判断内容一致的记录是否还需要调整 TTL/线路/优先级
+func (a *Aliyun) inSync(record *alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord) bool {
+	req := a.updateRequest(record, *record.Value)
+	return tea.Int64Value(req.TTL) == tea.Int64Value(record.TTL) &&
+		tea.StringValue(req.Line) == tea.StringValue(record.Line) &&
+		tea.Int64Value(req.Priority) == tea.Int64Value(record.Priority)
+}
+
+// setRemark 写入配置的备注与归属标记，内容不变时跳过
+func (a *Aliyun) setRemark(client aliyunAPI, recordID, remark string, created bool) error {
+	desired := a.Options.note(a.Ownership, remark)
+	if created {
+		desired = a.Ownership.tagged(a.Options.Comment)
 	}
-	if logInfo != nil {
-		logInfo("Aliyun: record created: %s => %s", fqdn, ip)
+	if desired == remark {
+		return nil
+	}
+	_, err := client.UpdateDomainRecordRemark(&alidns.UpdateDomainRecordRemarkRequest{
+		RecordId: tea.String(recordID),
+		Remark:   tea.String(desired),
+	})
+	if err != nil {
+		logError("Aliyun: set remark failed for record %s: %v", recordID, err)
+		return fmt.Errorf("set remark failed: %v", err)
 	}
+	logDebug("Aliyun: remark set for record %s", recordID)
 	return nil
 }
@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	alidns "github.com/alibabacloud-go/alidns-20150109/v4/client"
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+// fakeAliyun 记录调用的云解析接口，remarkFailures 为前几次写备注返回错误的次数
+type fakeAliyun struct {
+	aliyunAPI
+	records        []*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord
+	remarkFailures int
+	remarkCalls    int
+	remarks        map[string]string
+	added          []string
+	updated        []string
+	deleted        []string
+}
+
+func (f *fakeAliyun) DescribeSubDomainRecords(*alidns.DescribeSubDomainRecordsRequest) (*alidns.DescribeSubDomainRecordsResponse, error) {
+	return &alidns.DescribeSubDomainRecordsResponse{Body: &alidns.DescribeSubDomainRecordsResponseBody{
+		DomainRecords: &alidns.DescribeSubDomainRecordsResponseBodyDomainRecords{Record: f.records},
+	}}, nil
+}
+
+func (f *fakeAliyun) AddDomainRecord(req *alidns.AddDomainRecordRequest) (*alidns.AddDomainRecordResponse, error) {
+	f.added = append(f.added, tea.StringValue(req.Value))
+	return &alidns.AddDomainRecordResponse{Body: &alidns.AddDomainRecordResponseBody{RecordId: tea.String("new-1")}}, nil
+}
+
+func (f *fakeAliyun) UpdateDomainRecord(req *alidns.UpdateDomainRecordRequest) (*alidns.UpdateDomainRecordResponse, error) {
+	f.updated = append(f.updated, tea.StringValue(req.RecordId))
+	return &alidns.UpdateDomainRecordResponse{}, nil
+}
+
+func (f *fakeAliyun) UpdateDomainRecordRemark(req *alidns.UpdateDomainRecordRemarkRequest) (*alidns.UpdateDomainRecordRemarkResponse, error) {
+	f.remarkCalls++
+	if f.remarkCalls <= f.remarkFailures {
+		return nil, errors.New("Throttling.User")
+	}
+	if f.remarks == nil {
+		f.remarks = make(map[string]string)
+	}
+	f.remarks[tea.StringValue(req.RecordId)] = tea.StringValue(req.Remark)
+	return &alidns.UpdateDomainRecordRemarkResponse{}, nil
+}
+
+func (f *fakeAliyun) DeleteDomainRecord(req *alidns.DeleteDomainRecordRequest) (*alidns.DeleteDomainRecordResponse, error) {
+	f.deleted = append(f.deleted, tea.StringValue(req.RecordId))
+	return &alidns.DeleteDomainRecordResponse{}, nil
+}
+
+func newTestAliyun(api aliyunAPI) *Aliyun {
+	remarkRetryDelay = 0
+	return &Aliyun{
+		Domain:    "example.com",
+		Subdomain: "home",
+		Ownership: Ownership{Marker: OwnershipMarker("home")},
+		client:    api,
+	}
+}
+
+func TestAliyunCreateRetriesRemark(t *testing.T) {
+	api := &fakeAliyun{remarkFailures: remarkAttempts - 1}
+	changes, err := newTestAliyun(api).UpdateRecord(context.Background(), "203.0.113.7", "A")
+	if err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Action != ActionCreate || changes[0].RecordID != "new-1" {
+		t.Fatalf("changes = %+v, want one create of new-1", changes)
+	}
+	if api.remarkCalls != remarkAttempts {
+		t.Errorf("remark calls = %d, want %d", api.remarkCalls, remarkAttempts)
+	}
+	if got := api.remarks["new-1"]; got != OwnershipMarker("home") {
+		t.Errorf("remark = %q, want ownership marker", got)
+	}
+	if len(api.deleted) != 0 {
+		t.Errorf("deleted = %v, want none", api.deleted)
+	}
+}
+
+func TestAliyunCreateRemovesUntaggedRecord(t *testing.T) {
+	api := &fakeAliyun{remarkFailures: remarkAttempts}
+	changes, err := newTestAliyun(api).UpdateRecord(context.Background(), "203.0.113.7", "A")
+	if err == nil {
+		t.Fatal("UpdateRecord succeeded, want error when the record cannot be tagged")
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes = %+v, want none after rollback", changes)
+	}
+	if len(api.deleted) != 1 || api.deleted[0] != "new-1" {
+		t.Errorf("deleted = %v, want [new-1]", api.deleted)
+	}
+}
+
+func TestAliyunCreateWithoutOwnershipKeepsRecord(t *testing.T) {
+	api := &fakeAliyun{remarkFailures: remarkAttempts}
+	a := newTestAliyun(api)
+	a.Ownership = Ownership{}
+	a.Options.Comment = "nas"
+	changes, err := a.UpdateRecord(context.Background(), "203.0.113.7", "A")
+	if err == nil {
+		t.Fatal("UpdateRecord succeeded, want the remark error")
+	}
+	if len(changes) != 1 || changes[0].Action != ActionCreate || changes[0].RecordID != "new-1" {
+		t.Errorf("changes = %+v, want the create of new-1 reported with the error", changes)
+	}
+	if len(api.deleted) != 0 {
+		t.Errorf("deleted = %v, want the record kept when ownership is disabled", api.deleted)
+	}
+}
+
+// aliyunRecord 构造 home.example.com 下的一条 A 记录
+func aliyunRecord(id, value string, ttl int64, remark string) *alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord {
+	return &alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord{
+		RecordId: tea.String(id),
+		RR:       tea.String("home"),
+		Type:     tea.String("A"),
+		Value:    tea.String(value),
+		TTL:      tea.Int64(ttl),
+		Line:     tea.String("default"),
+		Remark:   tea.String(remark),
+	}
+}
+
+func TestAliyunPlan(t *testing.T) {
+	marker := OwnershipMarker("home")
+	tests := []struct {
+		name    string
+		records []*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord
+		options RecordOptions
+		delete  bool
+		want    []Change
+		wantErr error
+	}{
+		{"create", nil, RecordOptions{TTL: 60, Comment: "nas"}, false,
+			[]Change{{Action: ActionCreate, Name: "home.example.com", Type: "A", New: "203.0.113.7", TTL: 60, Comment: "nas " + marker}}, nil},
+		{"update ip and ttl", []*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord{aliyunRecord("r1", "198.51.100.1", 600, marker)},
+			RecordOptions{TTL: 60}, false,
+			[]Change{{Action: ActionUpdate, Name: "home.example.com", Type: "A", RecordID: "r1", Old: "198.51.100.1", New: "203.0.113.7", OldTTL: 600, TTL: 60, Comment: marker}}, nil},
+		{"update keeps ttl", []*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord{aliyunRecord("r1", "198.51.100.1", 600, marker)},
+			RecordOptions{}, false,
+			[]Change{{Action: ActionUpdate, Name: "home.example.com", Type: "A", RecordID: "r1", Old: "198.51.100.1", New: "203.0.113.7", OldTTL: 600, TTL: 600, Comment: marker}}, nil},
+		{"same ip in sync", []*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord{aliyunRecord("r1", "203.0.113.7", 600, marker)},
+			RecordOptions{}, false, nil, nil},
+		{"same ip ttl differs", []*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord{aliyunRecord("r1", "203.0.113.7", 600, marker)},
+			RecordOptions{TTL: 60}, false,
+			[]Change{{Action: ActionUpdate, Name: "home.example.com", Type: "A", RecordID: "r1", Old: "203.0.113.7", New: "203.0.113.7", OldTTL: 600, TTL: 60, Comment: marker}}, nil},
+		{"unowned", []*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord{aliyunRecord("r1", "198.51.100.1", 600, "")},
+			RecordOptions{}, false, nil, ErrNotOwned},
+		{"delete owned, skip unowned", []*alidns.DescribeSubDomainRecordsResponseBodyDomainRecordsRecord{
+			aliyunRecord("r1", "198.51.100.1", 600, marker), aliyunRecord("r2", "198.51.100.2", 600, "manual")},
+			RecordOptions{}, true,
+			[]Change{{Action: ActionDelete, Name: "home.example.com", Type: "A", RecordID: "r1", Old: "198.51.100.1", OldTTL: 600, Comment: marker}}, ErrNotOwned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAliyun{records: tt.records}
+			a := newTestAliyun(api)
+			a.Options = tt.options
+			var changes []Change
+			var err error
+			if tt.delete {
+				changes, err = a.PlanDelete(context.Background(), "A")
+			} else {
+				changes, err = a.PlanRecord(context.Background(), "203.0.113.7", "A")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(changes, tt.want) {
+				t.Errorf("changes:\n got %+v\nwant %+v", changes, tt.want)
+			}
+			if len(api.added)+len(api.updated)+len(api.deleted)+api.remarkCalls > 0 {
+				t.Errorf("plan made changes: added %v, updated %v, deleted %v, remark calls %d", api.added, api.updated, api.deleted, api.remarkCalls)
+			}
+		})
+	}
+}
+
+// blockingAliyun 查询接口一直阻塞，模拟没有响应的 API
+type blockingAliyun struct {
+	fakeAliyun
+	release chan struct{}
+}
+
+func (b *blockingAliyun) DescribeSubDomainRecords(req *alidns.DescribeSubDomainRecordsRequest) (*alidns.DescribeSubDomainRecordsResponse, error) {
+	<-b.release
+	return b.fakeAliyun.DescribeSubDomainRecords(req)
+}
+
+func TestAliyunCancelledContext(t *testing.T) {
+	api := &blockingAliyun{release: make(chan struct{})}
+	defer close(api.release)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	done := make(chan error, 1)
+	go func() {
+		_, err := newTestAliyun(api).UpdateRecord(ctx, "203.0.113.7", "A")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("UpdateRecord error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("UpdateRecord did not return after the context was cancelled")
+	}
+	if len(api.added) != 0 {
+		t.Errorf("added = %v, want no request after cancellation", api.added)
+	}
+}
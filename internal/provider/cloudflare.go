@@ -47,10 +47,13 @@ type Cloudflare struct {
 	ZoneID    string
 	Domain    string
 	Subdomain string
+	Ownership Ownership
+	Options   RecordOptions
+
+	baseURL string // 为空时使用官方 API 地址，测试时替换
 }
 
-func (c *Cloudflare) getZoneID(api *cloudflare.API) (string, error) {
-	ctx := context.Background()
+func (c *Cloudflare) getZoneID(ctx context.Context, api *cloudflare.API) (string, error) {
 	resp, err := api.ListZonesContext(ctx, cloudflare.WithZoneFilters(c.Domain, "", ""))
 	if err != nil {
 		logError("Cloudflare ListZonesContext error: %v", err)
@@ -66,81 +69,302 @@ func (c *Cloudflare) getZoneID(api *cloudflare.API) (string, error) {
 	return "", fmt.Errorf("zone not found for domain: %s", c.Domain)
 }
 
-func (c *Cloudflare) UpdateRecord(ip string, recordType string) error {
-	api, err := cloudflare.NewWithAPIToken(c.APIToken)
+// connect 创建 API 客户端并确定 zone
+func (c *Cloudflare) connect(ctx context.Context) (*cloudflare.API, *cloudflare.ResourceContainer, error) {
+	var opts []cloudflare.Option
+	if c.baseURL != "" {
+		opts = append(opts, cloudflare.BaseURL(c.baseURL))
+	}
+	api, err := cloudflare.NewWithAPIToken(c.APIToken, opts...)
 	if err != nil {
 		logError("Cloudflare API token error: %v", err)
-		return err
+		return nil, nil, err
 	}
-	ctx := context.Background()
 	zoneID := c.ZoneID
 	if zoneID == "" {
-		zoneID, err = c.getZoneID(api)
+		zoneID, err = c.getZoneID(ctx, api)
 		if err != nil {
-			return fmt.Errorf("auto get zone_id failed: %v", err)
+			return nil, nil, fmt.Errorf("auto get zone_id failed: %v", err)
 		}
 	}
-	rc := cloudflare.ZoneIdentifier(zoneID)
-	fqdn := fmt.Sprintf("%s.%s", c.Subdomain, c.Domain)
+	return api, cloudflare.ZoneIdentifier(zoneID), nil
+}
 
-	logDebug("Cloudflare update: fqdn=%s, ip=%s, type=%s", fqdn, ip, recordType)
+func (c *Cloudflare) UpdateRecord(ctx context.Context, ip string, recordType string) ([]Change, error) {
+	return c.reconcile(ctx, ip, recordType, false)
+}
+
+// PlanRecord 计算 UpdateRecord 将执行的变更，不修改任何记录
+func (c *Cloudflare) PlanRecord(ctx context.Context, ip string, recordType string) ([]Change, error) {
+	return c.reconcile(ctx, ip, recordType, true)
+}
+
+// DeleteRecord 删除该子域名下指定类型的记录，跳过没有归属标记的记录
+func (c *Cloudflare) DeleteRecord(ctx context.Context, recordType string) ([]Change, error) {
+	return c.remove(ctx, recordType, false)
+}
+
+// PlanDelete 计算 DeleteRecord 将执行的变更，不修改任何记录
+func (c *Cloudflare) PlanDelete(ctx context.Context, recordType string) ([]Change, error) {
+	return c.remove(ctx, recordType, true)
+}
+
+// ListZones 列出 API Token 可访问的 zone
+func (c *Cloudflare) ListZones(ctx context.Context) ([]Zone, error) {
+	api, err := cloudflare.NewWithAPIToken(c.APIToken)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := api.ListZones(ctx)
+	if err != nil {
+		logError("Cloudflare ListZones error: %v", err)
+		return nil, err
+	}
+	result := make([]Zone, 0, len(zones))
+	for _, z := range zones {
+		result = append(result, Zone{ID: z.ID, Name: z.Name})
+	}
+	return result, nil
+}
+
+// ListRecords 查询记录，subdomain 为空时返回整个 zone 的记录
+func (c *Cloudflare) ListRecords(ctx context.Context, subdomain string, recordType string) ([]Record, error) {
+	api, rc, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params := cloudflare.ListDNSRecordsParams{Type: recordType}
+	if subdomain != "" {
+		params.Name = fmt.Sprintf("%s.%s", subdomain, c.Domain)
+	}
+	records, _, err := api.ListDNSRecords(ctx, rc, params)
+	if err != nil {
+		logError("Cloudflare ListDNSRecords error: %v", err)
+		return nil, err
+	}
+	result := make([]Record, 0, len(records))
+	for _, record := range records {
+		result = append(result, Record{
+			ID:      record.ID,
+			Name:    record.Name,
+			Type:    record.Type,
+			Content: record.Content,
+			TTL:     record.TTL,
+			Proxied: record.Proxied,
+			Comment: record.Comment,
+			Owned:   c.Ownership.owns(record.Comment),
+		})
+	}
+	return result, nil
+}
+
+// listRecords 查询该子域名下指定类型的记录
+func (c *Cloudflare) listRecords(ctx context.Context, api *cloudflare.API, rc *cloudflare.ResourceContainer, recordType string) ([]cloudflare.DNSRecord, error) {
+	fqdn := fmt.Sprintf("%s.%s", c.Subdomain, c.Domain)
 	records, _, err := api.ListDNSRecords(ctx, rc, cloudflare.ListDNSRecordsParams{
 		Type: recordType,
 		Name: fqdn,
 	})
 	if err != nil {
 		logError("Cloudflare ListDNSRecords error: %v", err)
-		return err
+		return nil, err
+	}
+	var matched []cloudflare.DNSRecord
+	for _, record := range records {
+		if record.Type == recordType && record.Name == fqdn {
+			matched = append(matched, record)
+		}
+	}
+	return matched, nil
+}
+
+// reconcile 使记录指向 ip，dryRun 时只返回变更而不调用修改接口
+func (c *Cloudflare) reconcile(ctx context.Context, ip string, recordType string, dryRun bool) ([]Change, error) {
+	api, rc, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fqdn := fmt.Sprintf("%s.%s", c.Subdomain, c.Domain)
+
+	logDebug("Cloudflare update: fqdn=%s, ip=%s, type=%s", fqdn, ip, recordType)
+	records, err := c.listRecords(ctx, api, rc, recordType)
+	if err != nil {
+		return nil, err
 	}
 
 	var foundSame bool
+	var sameRecord cloudflare.DNSRecord
 	var toUpdate []cloudflare.DNSRecord
+	var skipped int
 	for _, record := range records {
-		if record.Type == recordType && record.Name == fqdn {
-			if record.Content == ip {
-				foundSame = true
-				break // 有完全一致的，直接跳过
-			}
-			toUpdate = append(toUpdate, record)
+		if !c.Ownership.canModify(record.Comment) {
+			logWarn("Cloudflare: skip record without ownership marker: %s (%s)", fqdn, record.ID)
+			skipped++
+			continue
+		}
+		if record.Content == ip {
+			foundSame = true
+			sameRecord = record
+			break // 有完全一致的，直接跳过
 		}
+		toUpdate = append(toUpdate, record)
 	}
 	if foundSame {
-		logInfo("Cloudflare: record already up-to-date: %s => %s", fqdn, ip)
-		return nil
+		if c.inSync(sameRecord) {
+			logInfo("Cloudflare: record already up-to-date: %s => %s", fqdn, ip)
+			return nil, nil
+		}
+		// IP 一致但 TTL/代理/备注或归属标记需要调整
+		toUpdate = []cloudflare.DNSRecord{sameRecord}
 	}
 	if len(toUpdate) > 0 {
+		var changes []Change
 		for _, record := range toUpdate {
-			updateParams := cloudflare.UpdateDNSRecordParams{
-				ID:      record.ID,
-				Type:    recordType,
-				Name:    fqdn,
-				Content: ip,
-				TTL:     record.TTL,
-				Proxied: record.Proxied,
+			params := c.updateParams(record, ip)
+			change := Change{
+				Action:   ActionUpdate,
+				Name:     fqdn,
+				Type:     recordType,
+				RecordID: record.ID,
+				Old:      record.Content,
+				New:      ip,
+				OldTTL:   record.TTL,
+				TTL:      params.TTL,
+				Proxied:  params.Proxied,
+				Comment:  record.Comment,
+			}
+			if params.Comment != nil {
+				change.Comment = *params.Comment
+			}
+			changes = append(changes, change)
+			if dryRun {
+				continue
 			}
-			_, err = api.UpdateDNSRecord(ctx, rc, updateParams)
+			_, err = api.UpdateDNSRecord(ctx, rc, params)
 			if err != nil {
 				logError("Cloudflare update record failed: %v", err)
-				return err
+				return changes, err
 			}
 			logInfo("Cloudflare updated record: %s => %s", fqdn, ip)
 		}
-		return nil
+		return changes, nil
+	}
+	if skipped > 0 {
+		logError("Cloudflare: refusing to modify %d unowned record(s) for %s", skipped, fqdn)
+		return nil, fmt.Errorf("%s: %w", fqdn, ErrNotOwned)
 	}
 	// 没有同名记录，自动添加
+	ttl := c.Options.TTL
+	if ttl == 0 {
+		ttl = 60
+	}
 	proxied := false
+	if c.Options.Proxied != nil {
+		proxied = *c.Options.Proxied
+	}
 	createParams := cloudflare.CreateDNSRecordParams{
 		Type:    recordType,
 		Name:    fqdn,
 		Content: ip,
-		TTL:     60,
+		TTL:     ttl,
+		Proxied: &proxied,
+		Comment: c.Ownership.tagged(c.Options.Comment),
+	}
+	changes := []Change{{
+		Action:  ActionCreate,
+		Name:    fqdn,
+		Type:    recordType,
+		New:     ip,
+		TTL:     ttl,
 		Proxied: &proxied,
+		Comment: createParams.Comment,
+	}}
+	if dryRun {
+		return changes, nil
 	}
 	_, err = api.CreateDNSRecord(ctx, rc, createParams)
 	if err != nil {
 		logError("Cloudflare create record failed: %v", err)
-		return fmt.Errorf("record not found and create failed: %v", err)
+		return changes, fmt.Errorf("record not found and create failed: %v", err)
 	}
 	logInfo("Cloudflare created new record: %s => %s", fqdn, ip)
-	return nil
+	return changes, nil
+}
+
+// remove 删除指定类型的记录，dryRun 时只返回变更而不调用修改接口
+func (c *Cloudflare) remove(ctx context.Context, recordType string, dryRun bool) ([]Change, error) {
+	api, rc, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fqdn := fmt.Sprintf("%s.%s", c.Subdomain, c.Domain)
+	records, err := c.listRecords(ctx, api, rc, recordType)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	var skipped int
+	for _, record := range records {
+		if !c.Ownership.canModify(record.Comment) {
+			logWarn("Cloudflare: skip deleting record without ownership marker: %s (%s)", fqdn, record.ID)
+			skipped++
+			continue
+		}
+		changes = append(changes, Change{
+			Action:   ActionDelete,
+			Name:     fqdn,
+			Type:     recordType,
+			RecordID: record.ID,
+			Old:      record.Content,
+			OldTTL:   record.TTL,
+			Proxied:  record.Proxied,
+			Comment:  record.Comment,
+		})
+		if dryRun {
+			continue
+		}
+		if err := api.DeleteDNSRecord(ctx, rc, record.ID); err != nil {
+			logError("Cloudflare delete record failed: %v", err)
+			return changes, err
+		}
+		logInfo("Cloudflare deleted record: %s %s %s", fqdn, recordType, record.Content)
+	}
+	if skipped > 0 {
+		return changes, fmt.Errorf("%s: %w", fqdn, ErrNotOwned)
+	}
+	return changes, nil
+}
+
+// updateParams 根据现有记录和配置生成更新参数，未配置的属性保持原值
+func (c *Cloudflare) updateParams(record cloudflare.DNSRecord, content string) cloudflare.UpdateDNSRecordParams {
+	params := cloudflare.UpdateDNSRecordParams{
+		ID:      record.ID,
+		Type:    record.Type,
+		Name:    record.Name,
+		Content: content,
+		TTL:     record.TTL,
+		Proxied: record.Proxied,
+		Tags:    record.Tags,
+	}
+	if !c.Options.PreserveExisting {
+		if c.Options.TTL != 0 {
+			params.TTL = c.Options.TTL
+		}
+		if c.Options.Proxied != nil {
+			params.Proxied = c.Options.Proxied
+		}
+	}
+	if comment := c.Options.note(c.Ownership, record.Comment); comment != record.Comment {
+		params.Comment = &comment
+	}
+	return params
+}
+
+// inSync 判断内容一致的记录是否还需要调整其它属性
+func (c *Cloudflare) inSync(record cloudflare.DNSRecord) bool {
+	params := c.updateParams(record, record.Content)
+	if params.Comment != nil || params.TTL != record.TTL {
+		return false
+	}
+	return cloudflare.Bool(params.Proxied) == cloudflare.Bool(record.Proxied)
 }
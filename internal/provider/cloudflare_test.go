@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+func TestCloudflareUpdateParams(t *testing.T) {
+	marker := OwnershipMarker("home")
+	record := func(ttl int, proxied *bool, comment string) cloudflare.DNSRecord {
+		return cloudflare.DNSRecord{ID: "rec1", Type: "A", Name: "home.example.com", Content: "203.0.113.7", TTL: ttl, Proxied: proxied, Comment: comment}
+	}
+	tests := []struct {
+		name        string
+		options     RecordOptions
+		ownership   Ownership
+		record      cloudflare.DNSRecord
+		wantTTL     int
+		wantProxied *bool
+		wantComment string // 为空表示不修改备注
+		wantInSync  bool
+	}{
+		{"options unset keep existing", RecordOptions{}, Ownership{},
+			record(300, cloudflare.BoolPtr(true), "nas"), 300, cloudflare.BoolPtr(true), "", true},
+		{"options unset with auto ttl", RecordOptions{}, Ownership{},
+			record(1, nil, ""), 1, nil, "", true},
+		{"ttl only", RecordOptions{TTL: 60}, Ownership{},
+			record(300, cloudflare.BoolPtr(true), ""), 60, cloudflare.BoolPtr(true), "", false},
+		{"ttl already set", RecordOptions{TTL: 300}, Ownership{},
+			record(300, cloudflare.BoolPtr(false), ""), 300, cloudflare.BoolPtr(false), "", true},
+		{"proxied only", RecordOptions{Proxied: cloudflare.BoolPtr(false)}, Ownership{},
+			record(300, cloudflare.BoolPtr(true), ""), 300, cloudflare.BoolPtr(false), "", false},
+		{"proxied false matches unset", RecordOptions{Proxied: cloudflare.BoolPtr(false)}, Ownership{},
+			record(300, nil, ""), 300, cloudflare.BoolPtr(false), "", true},
+		{"preserve existing ignores ttl proxied and comment",
+			RecordOptions{TTL: 60, Proxied: cloudflare.BoolPtr(true), Comment: "home router", PreserveExisting: true}, Ownership{},
+			record(300, cloudflare.BoolPtr(false), "nas"), 300, cloudflare.BoolPtr(false), "", true},
+		{"preserve existing still adds marker", RecordOptions{PreserveExisting: true}, Ownership{Marker: marker},
+			record(300, nil, "nas"), 300, nil, "nas " + marker, false},
+		{"comment change", RecordOptions{Comment: "home router"}, Ownership{},
+			record(300, nil, "old"), 300, nil, "home router", false},
+		{"comment unchanged", RecordOptions{Comment: "home router"}, Ownership{},
+			record(300, nil, "home router"), 300, nil, "", true},
+		{"marker missing", RecordOptions{}, Ownership{Marker: marker},
+			record(300, nil, ""), 300, nil, marker, false},
+		{"comment and marker present", RecordOptions{Comment: "home router"}, Ownership{Marker: marker},
+			record(300, nil, "home router "+marker), 300, nil, "", true},
+		{"comment change keeps marker", RecordOptions{Comment: "home router"}, Ownership{Marker: marker},
+			record(300, nil, "old "+marker), 300, nil, "home router " + marker, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cloudflare{Options: tt.options, Ownership: tt.ownership}
+			params := c.updateParams(tt.record, "198.51.100.1")
+			if params.ID != "rec1" || params.Type != "A" || params.Name != "home.example.com" || params.Content != "198.51.100.1" {
+				t.Errorf("identity = %s %s %s %s", params.ID, params.Type, params.Name, params.Content)
+			}
+			if params.TTL != tt.wantTTL {
+				t.Errorf("TTL = %d, want %d", params.TTL, tt.wantTTL)
+			}
+			if (params.Proxied == nil) != (tt.wantProxied == nil) || cloudflare.Bool(params.Proxied) != cloudflare.Bool(tt.wantProxied) {
+				t.Errorf("Proxied = %v, want %v", params.Proxied, tt.wantProxied)
+			}
+			switch {
+			case tt.wantComment == "" && params.Comment != nil:
+				t.Errorf("Comment = %q, want unchanged", *params.Comment)
+			case tt.wantComment != "" && (params.Comment == nil || *params.Comment != tt.wantComment):
+				t.Errorf("Comment = %v, want %q", params.Comment, tt.wantComment)
+			}
+			if got := c.inSync(tt.record); got != tt.wantInSync {
+				t.Errorf("inSync() = %v, want %v", got, tt.wantInSync)
+			}
+		})
+	}
+}
+
+// fakeCloudflareAPI 只响应记录查询的 Cloudflare API，收到其它请求时让测试失败
+func fakeCloudflareAPI(t *testing.T, records []cloudflare.DNSRecord) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/zones/zone1/dns_records" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.Error(w, "unexpected", http.StatusInternalServerError)
+			return
+		}
+		if records == nil {
+			records = []cloudflare.DNSRecord{}
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"success":     true,
+			"errors":      []any{},
+			"messages":    []any{},
+			"result":      records,
+			"result_info": map[string]int{"page": 1, "per_page": 100, "count": len(records), "total_count": len(records), "total_pages": 1},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCloudflarePlan(t *testing.T) {
+	marker := OwnershipMarker("home")
+	record := func(id, content string, ttl int, proxied bool, comment string) cloudflare.DNSRecord {
+		return cloudflare.DNSRecord{ID: id, Type: "A", Name: "home.example.com", Content: content, TTL: ttl, Proxied: cloudflare.BoolPtr(proxied), Comment: comment}
+	}
+	tests := []struct {
+		name    string
+		records []cloudflare.DNSRecord
+		options RecordOptions
+		delete  bool
+		want    []Change
+		wantErr error
+	}{
+		{"create with defaults", nil, RecordOptions{}, false,
+			[]Change{{Action: ActionCreate, Name: "home.example.com", Type: "A", New: "203.0.113.7", TTL: 60, Proxied: cloudflare.BoolPtr(false), Comment: marker}}, nil},
+		{"create with options", nil, RecordOptions{TTL: 300, Proxied: cloudflare.BoolPtr(true), Comment: "nas"}, false,
+			[]Change{{Action: ActionCreate, Name: "home.example.com", Type: "A", New: "203.0.113.7", TTL: 300, Proxied: cloudflare.BoolPtr(true), Comment: "nas " + marker}}, nil},
+		{"update ip keeps ttl and proxied", []cloudflare.DNSRecord{record("r1", "198.51.100.1", 120, true, marker)}, RecordOptions{}, false,
+			[]Change{{Action: ActionUpdate, Name: "home.example.com", Type: "A", RecordID: "r1", Old: "198.51.100.1", New: "203.0.113.7", OldTTL: 120, TTL: 120, Proxied: cloudflare.BoolPtr(true), Comment: marker}}, nil},
+		{"update ip ttl and proxied", []cloudflare.DNSRecord{record("r1", "198.51.100.1", 120, true, marker)},
+			RecordOptions{TTL: 60, Proxied: cloudflare.BoolPtr(false)}, false,
+			[]Change{{Action: ActionUpdate, Name: "home.example.com", Type: "A", RecordID: "r1", Old: "198.51.100.1", New: "203.0.113.7", OldTTL: 120, TTL: 60, Proxied: cloudflare.BoolPtr(false), Comment: marker}}, nil},
+		{"same ip in sync", []cloudflare.DNSRecord{record("r1", "203.0.113.7", 120, true, marker)}, RecordOptions{}, false, nil, nil},
+		{"same ip proxied differs", []cloudflare.DNSRecord{record("r1", "203.0.113.7", 120, true, marker)},
+			RecordOptions{Proxied: cloudflare.BoolPtr(false)}, false,
+			[]Change{{Action: ActionUpdate, Name: "home.example.com", Type: "A", RecordID: "r1", Old: "203.0.113.7", New: "203.0.113.7", OldTTL: 120, TTL: 120, Proxied: cloudflare.BoolPtr(false), Comment: marker}}, nil},
+		{"unowned", []cloudflare.DNSRecord{record("r1", "198.51.100.1", 120, false, "manual")}, RecordOptions{}, false, nil, ErrNotOwned},
+		{"delete owned, skip unowned", []cloudflare.DNSRecord{record("r1", "198.51.100.1", 120, true, marker), record("r2", "198.51.100.2", 120, false, "manual")},
+			RecordOptions{}, true,
+			[]Change{{Action: ActionDelete, Name: "home.example.com", Type: "A", RecordID: "r1", Old: "198.51.100.1", OldTTL: 120, Proxied: cloudflare.BoolPtr(true), Comment: marker}}, ErrNotOwned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cloudflare{
+				APIToken:  "test-token",
+				ZoneID:    "zone1",
+				Domain:    "example.com",
+				Subdomain: "home",
+				Ownership: Ownership{Marker: marker},
+				Options:   tt.options,
+				baseURL:   fakeCloudflareAPI(t, tt.records).URL,
+			}
+			var changes []Change
+			var err error
+			if tt.delete {
+				changes, err = c.PlanDelete(context.Background(), "A")
+			} else {
+				changes, err = c.PlanRecord(context.Background(), "203.0.113.7", "A")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(changes, tt.want) {
+				t.Errorf("changes:\n got %s\nwant %s", formatChanges(changes), formatChanges(tt.want))
+			}
+		})
+	}
+}
+
+// formatChanges 输出变更的可读形式，指针字段显示为值
+func formatChanges(changes []Change) string {
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		lines[i] = c.String()
+	}
+	return "[" + strings.Join(lines, "; ") + "]"
+}
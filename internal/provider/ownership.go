@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotOwned 记录存在但没有 OpenDDNS 归属标记
+var ErrNotOwned = errors.New("record is not owned by OpenDDNS")
+
+// Ownership 记录归属标记设置，Marker 为空表示不启用
+type Ownership struct {
+	Marker       string
+	AllowUnowned bool // 允许修改并接管未标记的记录
+}
+
+// OwnershipMarker 生成写入记录备注的归属标记
+func OwnershipMarker(ownerID string) string {
+	if ownerID == "" {
+		ownerID = "default"
+	}
+	return "heritage=openddns,openddns/owner=" + ownerID
+}
+
+func (o Ownership) enabled() bool {
+	return o.Marker != ""
+}
+
+// owns 判断记录备注中是否含有归属标记，未启用时视为全部归属
+func (o Ownership) owns(note string) bool {
+	return !o.enabled() || hasMarker(note, o.Marker)
+}
+
+// hasMarker 判断备注中是否含有完整的标记：前后只能是开头、结尾、空白、逗号或分号，
+// 避免 owner=home 把 owner=home-router 的记录当作自己的
+func hasMarker(note, marker string) bool {
+	for i := 0; i < len(note); {
+		j := strings.Index(note[i:], marker)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(marker)
+		if (start == 0 || isMarkerDelim(note[start-1])) && (end == len(note) || isMarkerDelim(note[end])) {
+			return true
+		}
+		i = start + 1
+	}
+	return false
+}
+
+func isMarkerDelim(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', ',', ';':
+		return true
+	}
+	return false
+}
+
+// canModify 判断是否允许修改该记录
+func (o Ownership) canModify(note string) bool {
+	return o.owns(note) || o.AllowUnowned
+}
+
+// tagged 返回追加了归属标记的备注，已含标记时原样返回
+func (o Ownership) tagged(note string) string {
+	if !o.enabled() || hasMarker(note, o.Marker) {
+		return note
+	}
+	if note == "" {
+		return o.Marker
+	}
+	return note + " " + o.Marker
+}
@@ -0,0 +1,54 @@
+package provider
+
+import "testing"
+
+func TestOwnershipOwns(t *testing.T) {
+	home := Ownership{Marker: OwnershipMarker("home")}
+	tests := []struct {
+		name string
+		own  Ownership
+		note string
+		want bool
+	}{
+		{"disabled owns everything", Ownership{}, "anything", true},
+		{"exact marker", home, "heritage=openddns,openddns/owner=home", true},
+		{"marker after comment", home, "office router heritage=openddns,openddns/owner=home", true},
+		{"marker before comment", home, "heritage=openddns,openddns/owner=home; do not edit", true},
+		{"empty note", home, "", false},
+		{"other owner", home, "heritage=openddns,openddns/owner=office", false},
+		{"owner with shared prefix", home, "heritage=openddns,openddns/owner=home-router", false},
+		{"owner with shared prefix then own marker", home,
+			"heritage=openddns,openddns/owner=home-router heritage=openddns,openddns/owner=home", true},
+		{"marker embedded in word", home, "xheritage=openddns,openddns/owner=home", false},
+		{"default owner", Ownership{Marker: OwnershipMarker("")}, "heritage=openddns,openddns/owner=default", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.own.owns(tt.note); got != tt.want {
+				t.Errorf("owns(%q) = %v, want %v", tt.note, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOwnershipTagged(t *testing.T) {
+	home := Ownership{Marker: OwnershipMarker("home")}
+	tests := []struct {
+		note string
+		want string
+	}{
+		{"", "heritage=openddns,openddns/owner=home"},
+		{"nas", "nas heritage=openddns,openddns/owner=home"},
+		{"nas heritage=openddns,openddns/owner=home", "nas heritage=openddns,openddns/owner=home"},
+		{"heritage=openddns,openddns/owner=home-router",
+			"heritage=openddns,openddns/owner=home-router heritage=openddns,openddns/owner=home"},
+	}
+	for _, tt := range tests {
+		if got := home.tagged(tt.note); got != tt.want {
+			t.Errorf("tagged(%q) = %q, want %q", tt.note, got, tt.want)
+		}
+	}
+	if got := (Ownership{}).tagged("nas"); got != "nas" {
+		t.Errorf("disabled tagged = %q, want unchanged", got)
+	}
+}
@@ -1,6 +1,200 @@
 package provider
 
-// DNSProvider 统一接口
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DNSProvider 统一接口，ctx 取消时中止进行中的请求，且不再发出后续请求
 type DNSProvider interface {
-	UpdateRecord(ip string, recordType string) error
+	// UpdateRecord 使记录指向 ip，返回实际执行的变更，记录已是最新时为空
+	UpdateRecord(ctx context.Context, ip string, recordType string) ([]Change, error)
+	// DeleteRecord 删除子域名下指定类型的记录
+	DeleteRecord(ctx context.Context, recordType string) ([]Change, error)
+	// PlanRecord / PlanDelete 计算对应操作将产生的变更，不调用任何修改接口
+	PlanRecord(ctx context.Context, ip string, recordType string) ([]Change, error)
+	PlanDelete(ctx context.Context, recordType string) ([]Change, error)
+	// ListRecords 查询记录，subdomain 为空时返回整个域名下的记录，recordType 为空时不限类型
+	ListRecords(ctx context.Context, subdomain string, recordType string) ([]Record, error)
+}
+
+// ZoneLister 可列出账号下托管的域名，init 向导用来验证凭证
+type ZoneLister interface {
+	ListZones(ctx context.Context) ([]Zone, error)
+}
+
+// Zone 服务商上托管的一个域名
+type Zone struct {
+	ID   string
+	Name string
+}
+
+// Record 服务商上的一条解析记录
+type Record struct {
+	ID      string
+	Name    string
+	Type    string
+	Content string
+	TTL     int
+	Proxied *bool  // 仅 Cloudflare
+	Line    string // 仅阿里云
+	Comment string
+	Owned   bool // 带有归属标记，未启用 ownership 时恒为 true
+}
+
+// 变更动作
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change 描述一条记录的变更，用于 dry-run 和 plan 输出
+type Change struct {
+	Action   string
+	Name     string
+	Type     string
+	RecordID string
+	Old      string
+	New      string
+	OldTTL   int
+	TTL      int
+	Proxied  *bool // 仅 Cloudflare
+	Comment  string
+}
+
+func (c Change) String() string {
+	var b strings.Builder
+	switch c.Action {
+	case ActionCreate:
+		fmt.Fprintf(&b, "+ create %s %s %s", c.Name, c.Type, c.New)
+	case ActionUpdate:
+		fmt.Fprintf(&b, "~ update %s %s %s → %s", c.Name, c.Type, c.Old, c.New)
+	case ActionDelete:
+		fmt.Fprintf(&b, "- delete %s %s %s", c.Name, c.Type, c.Old)
+	default:
+		fmt.Fprintf(&b, "? %s %s %s", c.Action, c.Name, c.Type)
+	}
+	if c.RecordID != "" {
+		fmt.Fprintf(&b, " [id %s]", c.RecordID)
+	}
+	switch {
+	case c.Action == ActionUpdate && c.OldTTL != c.TTL:
+		fmt.Fprintf(&b, " ttl=%d → %d", c.OldTTL, c.TTL)
+	case c.TTL != 0:
+		fmt.Fprintf(&b, " ttl=%d", c.TTL)
+	case c.OldTTL != 0:
+		fmt.Fprintf(&b, " ttl=%d", c.OldTTL)
+	}
+	if c.Proxied != nil {
+		fmt.Fprintf(&b, " proxied=%t", *c.Proxied)
+	}
+	if c.Comment != "" {
+		fmt.Fprintf(&b, " comment=%q", c.Comment)
+	}
+	return b.String()
+}
+
+// dryRun 只计算并报告变更、不修改记录的包装
+type dryRun struct {
+	DNSProvider
+	report func([]Change)
+}
+
+// NewDryRun 包装服务商，UpdateRecord/DeleteRecord 只计算变更并交给 report 输出
+func NewDryRun(p DNSProvider, report func([]Change)) DNSProvider {
+	return &dryRun{DNSProvider: p, report: report}
+}
+
+func (d *dryRun) UpdateRecord(ctx context.Context, ip string, recordType string) ([]Change, error) {
+	changes, err := d.PlanRecord(ctx, ip, recordType)
+	if err != nil {
+		return nil, err
+	}
+	d.report(changes)
+	return changes, nil
+}
+
+func (d *dryRun) DeleteRecord(ctx context.Context, recordType string) ([]Change, error) {
+	changes, err := d.PlanDelete(ctx, recordType)
+	if err != nil {
+		return nil, err
+	}
+	d.report(changes)
+	return changes, nil
+}
+
+// 服务商调用的操作名，用于统计
+const (
+	OpUpdate     = "update"
+	OpDelete     = "delete"
+	OpPlan       = "plan"
+	OpPlanDelete = "plan_delete"
+	OpList       = "list"
+)
+
+// observed 统计每次服务商调用耗时与结果的包装
+type observed struct {
+	next    DNSProvider
+	observe func(op string, d time.Duration, err error)
+}
+
+// NewObserved 包装服务商，每次调用结束后把操作名、耗时和错误交给 observe
+func NewObserved(p DNSProvider, observe func(op string, d time.Duration, err error)) DNSProvider {
+	return &observed{next: p, observe: observe}
+}
+
+func (o *observed) UpdateRecord(ctx context.Context, ip string, recordType string) ([]Change, error) {
+	start := time.Now()
+	changes, err := o.next.UpdateRecord(ctx, ip, recordType)
+	o.observe(OpUpdate, time.Since(start), err)
+	return changes, err
+}
+
+func (o *observed) DeleteRecord(ctx context.Context, recordType string) ([]Change, error) {
+	start := time.Now()
+	changes, err := o.next.DeleteRecord(ctx, recordType)
+	o.observe(OpDelete, time.Since(start), err)
+	return changes, err
+}
+
+func (o *observed) PlanRecord(ctx context.Context, ip string, recordType string) ([]Change, error) {
+	start := time.Now()
+	changes, err := o.next.PlanRecord(ctx, ip, recordType)
+	o.observe(OpPlan, time.Since(start), err)
+	return changes, err
+}
+
+func (o *observed) PlanDelete(ctx context.Context, recordType string) ([]Change, error) {
+	start := time.Now()
+	changes, err := o.next.PlanDelete(ctx, recordType)
+	o.observe(OpPlanDelete, time.Since(start), err)
+	return changes, err
+}
+
+func (o *observed) ListRecords(ctx context.Context, subdomain string, recordType string) ([]Record, error) {
+	start := time.Now()
+	records, err := o.next.ListRecords(ctx, subdomain, recordType)
+	o.observe(OpList, time.Since(start), err)
+	return records, err
+}
+
+// RecordOptions 记录的可选属性，零值表示创建时使用默认值、更新时保留现有值
+type RecordOptions struct {
+	TTL              int
+	Proxied          *bool  // 仅 Cloudflare
+	Comment          string // Cloudflare 注释 / 阿里云备注
+	Line             string // 仅阿里云，解析线路
+	Priority         int    // 仅阿里云，MX 优先级
+	PreserveExisting bool   // 更新时保留现有记录的 TTL、代理、备注等属性
+}
+
+// note 计算记录期望的备注内容，current 为现有备注
+func (o RecordOptions) note(own Ownership, current string) string {
+	if o.Comment != "" && !o.PreserveExisting {
+		return own.tagged(o.Comment)
+	}
+	return own.tagged(current)
 }
@@ -0,0 +1,122 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prefix 加密值的前缀，形如 enc:v1:<base64(salt|nonce|ciphertext)>
+const Prefix = "enc:v1:"
+
+const (
+	saltSize   = 16
+	keySize    = 32
+	iterations = 100000
+)
+
+var ErrEmptyKey = errors.New("empty encryption key")
+
+// IsEncrypted 判断配置值是否为加密值
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// deriveKey 由口令或密钥文件内容和盐派生 AES-256 密钥
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyKey
+	}
+	return pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt 使用 AES-256-GCM 加密明文，每次使用随机盐和 nonce
+func Encrypt(plaintext, passphrase string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := append(salt, nonce...)
+	out = gcm.Seal(out, nonce, []byte(plaintext), nil)
+	return Prefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Decrypt 解密 Encrypt 生成的值
+func Decrypt(value, passphrase string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("value does not start with %s", Prefix)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encoding: %v", err)
+	}
+	if len(data) < saltSize {
+		return "", errors.New("ciphertext too short")
+	}
+	key, err := deriveKey(passphrase, data[:saltSize])
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+// GenerateKey 生成随机密钥，用于写入密钥文件
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ReadKeyFile 读取密钥文件内容（去除首尾空白）
+func ReadKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("%s: %w", path, ErrEmptyKey)
+	}
+	return key, nil
+}
@@ -0,0 +1,97 @@
+package secret
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plaintext := range []string{"cf-token-123", "", "多字节 ✓", strings.Repeat("x", 4096)} {
+		encrypted, err := Encrypt(plaintext, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(encrypted) {
+			t.Fatalf("Encrypt() = %q, missing %s prefix", encrypted, Prefix)
+		}
+		got, err := Decrypt(encrypted, key)
+		if err != nil {
+			t.Fatalf("Decrypt(Encrypt(%q)): %v", plaintext, err)
+		}
+		if got != plaintext {
+			t.Errorf("round trip = %q, want %q", got, plaintext)
+		}
+	}
+}
+
+func TestEncryptRandomized(t *testing.T) {
+	a, _ := Encrypt("same", "passphrase")
+	b, _ := Encrypt("same", "passphrase")
+	if a == b {
+		t.Error("two encryptions of the same value should differ")
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	encrypted, err := Encrypt("cf-token-123", "right key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 翻转密文最后一个字节
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, Prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 1
+	tampered := Prefix + base64.StdEncoding.EncodeToString(data)
+	tests := []struct {
+		name  string
+		value string
+		key   string
+		want  string
+	}{
+		{"wrong key", encrypted, "wrong key", "wrong key or corrupted value"},
+		{"tampered", tampered, "right key", "wrong key or corrupted value"},
+		{"empty key", encrypted, "", ErrEmptyKey.Error()},
+		{"missing prefix", "plain", "right key", "does not start with"},
+		{"bad base64", Prefix + "!!!", "right key", "invalid encoding"},
+		{"too short", Prefix + "AAAA", "right key", "too short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decrypt(tt.value, tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Decrypt() = %q, %v; want error containing %q", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := Encrypt("x", ""); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("Encrypt with empty key: %v, want ErrEmptyKey", err)
+	}
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "openddns.key")
+	if err := os.WriteFile(path, []byte("  secret-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadKeyFile(path); err != nil || got != "secret-key" {
+		t.Errorf("ReadKeyFile() = %q, %v; want %q", got, err, "secret-key")
+	}
+	empty := filepath.Join(dir, "empty.key")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadKeyFile(empty); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("ReadKeyFile(empty) error = %v, want ErrEmptyKey", err)
+	}
+}
@@ -2,16 +2,19 @@ package main
 
 import (
 	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/history"
 	ipfetcher "OpenDDNS/internal/ip_fetcher"
 	"OpenDDNS/internal/logger"
+	"OpenDDNS/internal/metrics"
+	"OpenDDNS/internal/notify"
 	"OpenDDNS/internal/provider"
+	"context"
 	"encoding/json"
-	"flag"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -59,7 +62,7 @@ func checkUpdate(currentVersion string) {
 	client := &http.Client{Timeout: 5 * time.Second}
 	resp, err := client.Get(repoAPI)
 	if err != nil {
-		fmt.Println("[WARN] Failed to check update:", err)
+		logger.Warn("Failed to check update: %v", err)
 		return
 	}
 	defer resp.Body.Close()
@@ -68,157 +71,249 @@ func checkUpdate(currentVersion string) {
 		HTMLURL string `json:"html_url"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		fmt.Println("[WARN] Failed to parse update info:", err)
+		logger.Warn("Failed to parse update info: %v", err)
 		return
 	}
 	if data.TagName != "" && semverGreater(data.TagName, currentVersion) {
-		fmt.Printf("\033[33m[UPDATE] New version available: %s → %s\nDownload: %s\033[0m\n", currentVersion, data.TagName, data.HTMLURL)
+		logger.Warn("New version available: %s → %s, download: %s", currentVersion, data.TagName, data.HTMLURL)
 	} else {
-		fmt.Println("[INFO] You are using the latest version.")
+		logger.Info("You are using the latest version.")
 	}
 }
 
-func getMajorityIP(sources []config.IPSrc) string {
-	return getMajorityIPWithNetwork(sources, "")
+func getMajorityIP(ctx context.Context, sources []config.IPSrc) string {
+	return getMajorityIPWithNetwork(ctx, sources, "")
 }
 
 // getMajorityIPWithNetwork 获取多数IP，支持强制指定网络类型
-func getMajorityIPWithNetwork(sources []config.IPSrc, networkType string) string {
-	ipResults := make(map[string]string)
-	available := 0
-	for _, src := range sources {
-		var ip string
-		var err error
+func getMajorityIPWithNetwork(ctx context.Context, sources []config.IPSrc, networkType string) string {
+	ip, _ := voteIP(fetchAll(ctx, sources, networkType))
+	return ip
+}
 
-		if networkType != "" {
-			ip, err = ipfetcher.FetchIPWithNetwork(src, networkType)
+// 投票结果的判定方式
+const (
+	voteNone     = "none"
+	voteSingle   = "single"
+	voteMajority = "majority"
+	votePriority = "priority"
+)
+
+// fetchAll 依次查询所有 IP 源
+func fetchAll(ctx context.Context, sources []config.IPSrc, networkType string) []ipfetcher.FetchResult {
+	results := make([]ipfetcher.FetchResult, 0, len(sources))
+	for _, src := range sources {
+		result := ipfetcher.FetchIPDetail(ctx, src, networkType)
+		metrics.ObserveSource(src.Name, result.Latency, result.Err == nil)
+		log := logger.With(logger.KeySource, src.Name, logger.KeyDuration, result.Latency)
+		if result.Err == nil {
+			log.With(logger.KeyIP, result.IP).Debug("IP source returned an address.")
 		} else {
-			ip, err = ipfetcher.FetchIP(src)
+			log.With(logger.KeyError, result.Err).Warn("IP source failed.")
 		}
+		results = append(results, result)
+	}
+	return results
+}
 
-		if err == nil && ip != "" {
-			logger.Debug("IP source %s returned: %s", src.Name, ip)
-			ipResults[src.Name] = ip
-			available++
-		} else {
-			logger.Warn("IP source %s failed: %v", src.Name, err)
+// voteIP 多数者胜；没有多数时按配置顺序取第一个可用结果
+func voteIP(results []ipfetcher.FetchResult) (string, string) {
+	var valid []string
+	for _, r := range results {
+		if r.Err == nil && r.IP != "" {
+			valid = append(valid, r.IP)
 		}
 	}
-	if available == 0 {
+	if len(valid) == 0 {
 		logger.Error("No available IP sources.")
-		return ""
+		return "", voteNone
 	}
-	if available < 2 {
-		for _, ip := range ipResults {
-			logger.Debug("Only one valid IP: %s", ip)
-			return ip
-		}
+	if len(valid) < 2 {
+		logger.Debug("Only one valid IP: %s", valid[0])
+		return valid[0], voteSingle
 	}
 	ipCounts := make(map[string]int)
-	for _, ip := range ipResults {
+	for _, ip := range valid {
 		ipCounts[ip]++
 	}
 	var majorityIP string
 	maxCount := 0
-	for ip, count := range ipCounts {
-		if count > maxCount {
-			maxCount = count
+	for _, ip := range valid {
+		if ipCounts[ip] > maxCount {
+			maxCount = ipCounts[ip]
 			majorityIP = ip
 		}
 	}
 	if maxCount >= 2 {
 		logger.Debug("Majority IP: %s", majorityIP)
-		return majorityIP
+		return majorityIP, voteMajority
 	}
 	logger.Warn("IP conflict, using priority list.")
-	for _, src := range sources {
-		if ip, ok := ipResults[src.Name]; ok {
-			logger.Debug("Priority IP: %s", ip)
-			return ip
+	metrics.ObserveVoteConflict()
+	logger.Debug("Priority IP: %s", valid[0])
+	return valid[0], votePriority
+}
+
+// networkTypeFor 根据记录类型配置决定网络类型
+func networkTypeFor(recordType string) string {
+	switch strings.ToLower(recordType) {
+	case "a":
+		logger.Debug("Force using IPv4 network for A record")
+		return "ipv4"
+	case "aaaa":
+		logger.Debug("Force using IPv6 network for AAAA record")
+		return "ipv6"
+	default:
+		return "" // auto模式，不强制网络类型
+	}
+}
+
+// newProvider 根据配置创建 DNS 服务商
+func newProvider(cfg *config.Config) (provider.DNSProvider, error) {
+	var ownership provider.Ownership
+	if cfg.Ownership.Enabled {
+		ownership = provider.Ownership{
+			Marker:       provider.OwnershipMarker(cfg.Ownership.OwnerID),
+			AllowUnowned: cfg.Ownership.AllowUnowned,
 		}
 	}
-	return ""
+
+	options := provider.RecordOptions{
+		TTL:              cfg.RecordOptions.TTL,
+		Proxied:          cfg.RecordOptions.Proxied,
+		Comment:          cfg.RecordOptions.Comment,
+		Line:             cfg.RecordOptions.Line,
+		Priority:         cfg.RecordOptions.Priority,
+		PreserveExisting: cfg.RecordOptions.PreserveExisting,
+	}
+
+	switch cfg.Provider {
+	case "cloudflare":
+		return &provider.Cloudflare{
+			APIToken:  cfg.Cloudflare.APIToken,
+			ZoneID:    cfg.Cloudflare.ZoneID,
+			Domain:    cfg.Domain,
+			Subdomain: cfg.Subdomain,
+			Ownership: ownership,
+			Options:   options,
+		}, nil
+	case "aliyun":
+		return &provider.Aliyun{
+			AccessKeyID:     cfg.Aliyun.AccessKeyID,
+			AccessKeySecret: cfg.Aliyun.AccessKeySecret,
+			Domain:          cfg.Domain,
+			Subdomain:       cfg.Subdomain,
+			Endpoint:        cfg.Aliyun.Endpoint,
+			Ownership:       ownership,
+			Options:         options,
+		}, nil
+	// case "tencentcloud":
+	// 	... reserved for tencentcloud ...
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", cfg.Provider)
+	}
 }
 
-func main() {
-	// Parse config file flag, support -c and --config, and --no-check-update
-	var configPath string
+// loadConfig 加载配置文件并按配置初始化日志
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	applyLogging(cfg)
+	return cfg, nil
+}
+
+// readConfig 解析并校验配置文件，不修改日志设置
+func readConfig(path string) (*config.Config, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("config file %s not found, run `openddns init` to create one", path)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	return cfg, nil
+}
+
+// applyLogging 按配置设置日志输出、凭证屏蔽，并向各模块注入日志函数
+func applyLogging(cfg *config.Config) {
+	logger.SetSecrets(cfg.Secrets()...)
+	logger.SetLogLevel(cfg.LogLevel)
+	logger.SetFormat(cfg.LogFormat)
+	logger.SetRotation(logger.Rotation{
+		MaxSizeMB:   cfg.LogRotate.MaxSizeMB,
+		RotateHours: cfg.LogRotate.RotateHours,
+		MaxBackups:  cfg.LogRotate.MaxBackups,
+		MaxAgeDays:  cfg.LogRotate.MaxAgeDays,
+		Compress:    cfg.LogRotate.Compress,
+	})
+	logger.SetLogFile(cfg.LogFile)
+	logger.SetOutputs(cfg.LogOutput, logger.SyslogOptions{
+		Network:  cfg.LogSyslog.Network,
+		Address:  cfg.LogSyslog.Address,
+		Tag:      cfg.LogSyslog.Tag,
+		Facility: cfg.LogSyslog.Facility,
+	})
+	// Inject logger
+	ipfetcher.SetLogger(logger.Debug, logger.Warn, logger.Error)
+	pl := recordLogger(cfg)
+	provider.SetLogger(pl.Debug, pl.Info, pl.Warn, pl.Error)
+	notify.SetLogger(logger.Debug, logger.Warn)
+	history.SetLogger(logger.Warn)
+}
+
+// recordLogger 返回附带记录名和服务商字段的日志记录器
+func recordLogger(cfg *config.Config) *logger.Entry {
+	return logger.With(logger.KeyRecord, recordName(cfg), logger.KeyProvider, cfg.Provider)
+}
+
+// recordName 返回记录的完整域名
+func recordName(cfg *config.Config) string {
+	return cfg.Subdomain + "." + cfg.Domain
+}
+
+// cmdRun 常驻运行，按间隔检测 IP 并同步记录
+func cmdRun(args []string) int {
+	fs, configPath := newFlagSet("run")
 	var noCheckUpdate bool
-	flag.StringVar(&configPath, "c", "config.yml", "Path to config file")
-	flag.StringVar(&configPath, "config", "config.yml", "Path to config file")
-	flag.BoolVar(&noCheckUpdate, "no-check-update", false, "Skip update check")
-	flag.Parse()
+	var dryRun bool
+	var once bool
+	fs.BoolVar(&noCheckUpdate, "no-check-update", false, "Skip update check")
+	fs.BoolVar(&dryRun, "dry-run", false, "Detect IP and log planned DNS changes without applying them")
+	fs.BoolVar(&once, "once", false, "Run a single detection and update round, then exit")
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	return runDaemon(*configPath, noCheckUpdate, dryRun, once)
+}
+
+// cmdOnce 执行一轮检测与同步后按结果退出
+func cmdOnce(args []string) int {
+	fs, configPath := newFlagSet("once")
+	var dryRun bool
+	fs.BoolVar(&dryRun, "dry-run", false, "Detect IP and log planned DNS changes without applying them")
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	return runDaemon(*configPath, true, dryRun, true)
+}
 
+// printBanner 输出启动信息，非终端时不带颜色
+func printBanner(cfg *config.Config) {
 	// Print startup info in English with color, show version
 	blue := "\033[34m"
 	green := "\033[32m"
 	reset := "\033[0m"
+	if !logger.IsTerminal(os.Stdout) {
+		blue, green, reset = "", "", ""
+	}
 	fmt.Printf("%s==============================%s\n", blue, reset)
 	fmt.Printf("%s   OpenDDNS - Modern Multi-Cloud DDNS Tool%s\n", green, reset)
 	fmt.Printf("%s   Version: %s   Build: %s%s\n", blue, Version, BuildTime, reset)
 	fmt.Printf("%s   https://github.com/GloryRedstoneUnion/OpenDDNS%s\n", blue, reset)
 	fmt.Printf("%s==============================%s\n", blue, reset)
 
-	if !noCheckUpdate {
-		checkUpdate(Version)
-	}
-
-	// 检查配置文件是否存在，不存在则自动生成并退出
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		defaultConfig := `provider: "cloudflare"
-
-domain: "example.com"
-subdomain: "www"
-
-# DNS record type: A (IPv4), AAAA (IPv6), or auto (automatic detection)
-# A: Force IPv4 network access to all APIs
-# AAAA: Force IPv6 network access to all APIs  
-# auto: Let system choose the best network path
-record_type: "auto"
-
-log_level: "info"
-log_file: ""
-
-ip_sources:
-  - name: "bilibili"
-    url: "https://api.live.bilibili.com/xlive/web-room/v1/index/getIpInfo"
-    type: "json"
-    json_path: "data.addr"
-  - name: "cloudflare"
-    url: "https://www.cloudflare-cn.com/cdn-cgi/trace"
-    type: "trace"
-  # IPv6 sources (uncomment if you need IPv6 DDNS)
-  # - name: "ipify-ipv6"
-  #   url: "https://api64.ipify.org"
-  #   type: "text"
-  # - name: "icanhazip-ipv6" 
-  #   url: "https://ipv6.icanhazip.com"
-  #   type: "text"
-
-update_interval_minutes: 5
-
-cloudflare:
-  api_token: "YOUR_CLOUDFLARE_API_TOKEN"
-  zone_id: ""
-aliyun:
-  access_key_id: "YOUR_ALIYUN_ACCESS_KEY_ID"
-  access_key_secret: "YOUR_ALIYUN_ACCESS_KEY_SECRET"
-  endpoint: "alidns.aliyuncs.com"
-`
-		err := os.WriteFile(configPath, []byte(defaultConfig), 0644)
-		if err != nil {
-			log.Fatalf("Failed to create default config: %v", err)
-		}
-		logger.Info("No config file found. A default config.yml has been created. Please edit it before running OpenDDNS.")
-		fmt.Println("\033[33m[WARN] No config file found. A default config.yml has been created. Please edit it before running OpenDDNS.\033[0m")
-		time.Sleep(5 * time.Second)
-		return
-	}
-
-	cfg, err := config.LoadConfig(configPath)
-	if err != nil {
-		log.Fatalf("Error reading config: %v", err)
-	}
 	fmt.Printf("%sConfig file:%s %s%s.%s%s\n", green, reset, blue, cfg.Subdomain, cfg.Domain, reset)
 	fmt.Printf("%sDNS Provider:%s %s%s%s\n", green, reset, blue, cfg.Provider, reset)
 
@@ -238,95 +333,207 @@ aliyun:
 	fmt.Printf("%sIP Source Count:%s %s%d%s\n", green, reset, blue, len(cfg.IPSources), reset)
 	fmt.Printf("%sSupported DNS Providers:%s %sCloudflare, Alicloud%s\n", green, reset, blue, reset)
 	fmt.Printf("%s==============================%s\n", blue, reset)
+}
 
-	logger.SetLogLevel(cfg.LogLevel)
-	logger.SetLogFile(cfg.LogFile)
-	// Inject logger
-	ipfetcher.SetLogger(logger.Debug, logger.Warn, logger.Error)
-	provider.SetLogger(logger.Debug, logger.Info, logger.Warn, logger.Error)
+// runDaemon 打印启动信息并进入同步循环，once 时只执行一轮
+func runDaemon(configPath string, noCheckUpdate, dryRun, once bool) int {
+	// 配置文件不存在时提示使用 init 向导创建
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		logger.Error("No config file found at %s. Run `openddns init -c %s` to create one.", configPath, configPath)
+		return exitFailure
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		logger.Error("%v", err)
+		return exitFailure
+	}
+	// JSON 日志时不输出启动横幅，保证标准输出每行都是 JSON
+	if !strings.EqualFold(cfg.LogFormat, logger.FormatJSON) {
+		printBanner(cfg)
+	}
+
+	if !noCheckUpdate {
+		checkUpdate(Version)
+	}
 
 	// Log program start
 	logger.Info("Program started.")
 
-	// Handle exit signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-quit
-		logger.Info("Program exited.")
-		os.Exit(0)
-	}()
+	// 退出信号取消根 context，由主循环等待进行中的更新后再退出
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// 同步使用独立的 context，宽限期结束时才取消进行中的请求
+	roundCtx, cancelRounds := context.WithCancel(context.Background())
+	defer cancelRounds()
+	defer logger.Close()
 
-	var dnsProvider provider.DNSProvider
-	switch cfg.Provider {
-	case "cloudflare":
-		dnsProvider = &provider.Cloudflare{
-			APIToken:  cfg.Cloudflare.APIToken,
-			ZoneID:    cfg.Cloudflare.ZoneID,
-			Domain:    cfg.Domain,
-			Subdomain: cfg.Subdomain,
+	dnsProvider, err := buildProvider(cfg, dryRun)
+	if err != nil {
+		logger.Error("%v", err)
+		return exitFailure
+	}
+	if dryRun {
+		logger.Warn("Dry-run mode: DNS records will not be modified.")
+	}
+
+	status := newDaemonStatus(cfg, dryRun)
+	if path := historyPath(configPath, cfg); path != "" {
+		store, err := history.Open(path)
+		if err != nil {
+			logger.Error("Failed to open history file: %v", err)
+			return exitFailure
 		}
-	case "aliyun":
-		dnsProvider = &provider.Aliyun{
-			AccessKeyID:     cfg.Aliyun.AccessKeyID,
-			AccessKeySecret: cfg.Aliyun.AccessKeySecret,
-			Domain:          cfg.Domain,
-			Subdomain:       cfg.Subdomain,
-			Endpoint:        cfg.Aliyun.Endpoint,
+		status.setStore(store)
+	}
+	defer status.setStore(nil)
+	notifier, err := buildNotifier(cfg)
+	if err != nil {
+		logger.Error("%v", err)
+		return exitFailure
+	}
+	status.setNotifier(notifier)
+	// 先于历史文件关闭，等待未发送的通知
+	defer status.setNotifier(nil)
+	state := &syncState{cfg: cfg, provider: dnsProvider, dryRun: dryRun, status: status}
+	if once {
+		// 单次模式：执行一轮检测与同步后按结果退出
+		inflight := state.startRound(roundCtx)
+		select {
+		case result := <-inflight:
+			return exitCode(result, dryRun)
+		case <-ctx.Done():
+			stop()
+			result, finished := waitInFlight(inflight, shutdownGrace(state.cfg), cancelRounds)
+			logger.Info("Program exited.")
+			if !finished {
+				return exitFailure
+			}
+			return exitCode(result, dryRun)
 		}
-	// case "tencentcloud":
-	// 	... reserved for tencentcloud ...
-	default:
-		log.Fatalf("Unsupported provider: %s", cfg.Provider)
 	}
 
-	var lastIP string
-	// 关键元素染色
-	domainColor := "\033[36m"   // 青色
-	providerColor := "\033[35m" // 紫色
-	reset = "\033[0m"           // 修正为赋值，不再用 :=
-	logger.Info("DDNS service started for %s%s.%s%s with provider %s%s%s", domainColor, cfg.Subdomain, cfg.Domain, reset, providerColor, cfg.Provider, reset)
-	ticker := time.NewTicker(time.Duration(cfg.UpdateIntervalMinutes) * time.Minute)
+	recordLogger(cfg).Info("DDNS service started.")
+	metrics.SetBuildInfo(Version, BuildTime)
+	control := make(chan string, 8)
+	if err := startHTTPServer(ctx, cfg.HTTP, status, control); err != nil {
+		logger.Error("Failed to start HTTP server: %v", err)
+		return exitFailure
+	}
+	ticker := time.NewTicker(updateInterval(cfg))
 	defer ticker.Stop()
-	for ; true; <-ticker.C {
-		// 根据记录类型配置决定网络类型
-		var networkType string
-		switch strings.ToLower(cfg.RecordType) {
-		case "a":
-			networkType = "ipv4"
-			logger.Debug("Force using IPv4 network for A record")
-		case "aaaa":
-			networkType = "ipv6"
-			logger.Debug("Force using IPv6 network for AAAA record")
-		default:
-			networkType = "" // auto模式，不强制网络类型
+	status.scheduled(time.Now().Add(updateInterval(cfg)))
+
+	// SIGHUP 或配置文件变化时重新加载配置
+	reload := make(chan string, 1)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			// 先重新打开日志文件，兼容外部 logrotate 移走文件
+			if err := logger.Reopen(); err != nil {
+				logger.Warn("Failed to reopen log file: %v", err)
+			}
+			requestReload(reload, "SIGHUP")
+		}
+	}()
+	if cfg.WatchConfig {
+		if err := watchConfig(configPath, reload); err != nil {
+			logger.Warn("Failed to watch config file: %v", err)
 		}
+	}
 
-		newIP := getMajorityIPWithNetwork(cfg.IPSources, networkType)
-		if newIP == "" {
-			logger.Warn("Failed to determine public IP.")
-			continue
+	// 同步在后台执行，主循环始终能响应退出信号；进行中的一轮结束后才处理重新加载
+	var inflight <-chan int
+	pendingReload := ""
+	var pendingActions []string
+	runNow := true
+	for {
+		if inflight == nil {
+			if pendingReload != "" {
+				runNow = state.reload(configPath, pendingReload, ticker) || runNow
+				pendingReload = ""
+			}
+			for _, action := range pendingActions {
+				runNow = state.applyControl(action) || runNow
+			}
+			pendingActions = nil
+			if runNow {
+				inflight = state.startRound(roundCtx)
+				runNow = false
+			}
 		}
-		if newIP == lastIP {
-			logger.Debug("IP not changed: %s", newIP)
-			continue
+		select {
+		case <-ctx.Done():
+			// 恢复默认信号处理，再次按 Ctrl+C 可强制退出
+			stop()
+			_, finished := waitInFlight(inflight, shutdownGrace(state.cfg), cancelRounds)
+			logger.Info("Program exited.")
+			if !finished {
+				return exitFailure
+			}
+			return exitUnchanged
+		case <-inflight:
+			inflight = nil
+		case <-ticker.C:
+			runNow = true
+			status.scheduled(time.Now().Add(updateInterval(state.cfg)))
+		case reason := <-reload:
+			pendingReload = reason
+		case action := <-control:
+			pendingActions = append(pendingActions, action)
 		}
-		logger.Info("Detected public IP: %s", newIP)
+	}
+}
+
+// historyPath 返回历史文件路径，相对路径基于配置文件所在目录，未配置时为空
+func historyPath(configPath string, cfg *config.Config) string {
+	if cfg.HistoryFile == "" || filepath.IsAbs(cfg.HistoryFile) {
+		return cfg.HistoryFile
+	}
+	return filepath.Join(filepath.Dir(configPath), cfg.HistoryFile)
+}
+
+// updateInterval 返回同步间隔
+func updateInterval(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.UpdateIntervalMinutes) * time.Minute
+}
 
-		// 确定DNS记录类型
-		recordType := ipfetcher.DetermineRecordType(newIP, cfg.RecordType)
-		if recordType == "" {
-			logger.Error("Invalid IP address format: %s", newIP)
-			continue
+// buildProvider 创建服务商，dry-run 时包装为只输出变更的服务商
+func buildProvider(cfg *config.Config, dryRun bool) (provider.DNSProvider, error) {
+	dnsProvider, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dnsProvider = provider.NewObserved(dnsProvider, func(op string, d time.Duration, err error) {
+		metrics.ObserveProviderCall(cfg.Provider, op, d, err)
+	})
+	if !dryRun {
+		return dnsProvider, nil
+	}
+	return provider.NewDryRun(dnsProvider, func(changes []provider.Change) {
+		if len(changes) == 0 {
+			logger.Info("[DRY-RUN] No changes.")
 		}
-		logger.Debug("Using DNS record type: %s", recordType)
+		for _, change := range changes {
+			logger.Info("[DRY-RUN] %s", change)
+		}
+	}), nil
+}
 
-		err := dnsProvider.UpdateRecord(newIP, recordType)
-		if err != nil {
-			logger.Error("Error updating DNS record: %v", err)
-		} else {
-			logger.Info("DNS record updated successfully.")
-			lastIP = newIP
+func main() {
+	// 第一个参数不是选项时视为子命令，否则默认为 run，兼容旧的 `openddns -c config.yml` 用法
+	args := os.Args[1:]
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for _, cmd := range commandList() {
+		if cmd.name == name {
+			os.Exit(cmd.run(args))
 		}
 	}
+	fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+	printUsage()
+	os.Exit(exitFailure)
 }
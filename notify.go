@@ -0,0 +1,99 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/history"
+	"OpenDDNS/internal/notify"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 退出或重新加载时等待未发送通知的最长时间
+const notifyDrainTimeout = 10 * time.Second
+
+// 邮件未配置 events 时只即时发送失败和恢复，其余变化见每日摘要
+var emailDefaultEvents = []string{notify.EventUpdateFailed, notify.EventDetectionFailed, notify.EventRecovered}
+
+// newNotifier 按类型创建通知方式
+func newNotifier(n config.NotifierConfig) (notify.Notifier, error) {
+	switch strings.ToLower(n.Type) {
+	case "webhook":
+		return notify.NewWebhook(n.URL, n.Method, n.Headers, n.Body)
+	case "dingtalk":
+		return notify.NewDingTalk(n.URL, n.Secret), nil
+	case "feishu":
+		return notify.NewFeishu(n.URL, n.Secret), nil
+	case "wecom":
+		return notify.NewWeCom(n.URL), nil
+	case "telegram":
+		return notify.NewTelegram(n.URL, n.BotToken, n.ChatID), nil
+	case "slack":
+		return notify.NewSlack(n.URL), nil
+	case "discord":
+		return notify.NewDiscord(n.URL), nil
+	case "email":
+		return notify.NewEmail(notify.EmailOptions{
+			Host:     n.SMTP.Host,
+			Port:     n.SMTP.Port,
+			Security: n.SMTP.Security,
+			Username: n.SMTP.Username,
+			Password: n.SMTP.Password,
+			From:     n.SMTP.From,
+			To:       n.SMTP.To,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported notifier type: %s", n.Type)
+	}
+}
+
+// buildNotifier 根据 notify 配置创建发送队列，未配置时返回 nil
+func buildNotifier(cfg *config.Config) (*notify.Dispatcher, error) {
+	if len(cfg.Notify) == 0 {
+		return nil, nil
+	}
+	targets := make([]notify.Target, 0, len(cfg.Notify))
+	for i, n := range cfg.Notify {
+		notifier, err := newNotifier(n)
+		if err != nil {
+			return nil, fmt.Errorf("notify[%d]: %w", i, err)
+		}
+		name := n.Name
+		if name == "" {
+			name = strings.ToLower(n.Type)
+		}
+		target := notify.Target{
+			Name:     name,
+			Notifier: notifier,
+			Events:   n.Events,
+			Timeout:  time.Duration(n.TimeoutSeconds) * time.Second,
+		}
+		if strings.EqualFold(n.Type, "email") && len(n.Events) == 0 {
+			target.Events = emailDefaultEvents
+		}
+		if n.Digest != "" {
+			if target.DigestAt, err = notify.ParseDigestTime(n.Digest); err != nil {
+				return nil, fmt.Errorf("notify[%d].digest: %w", i, err)
+			}
+			target.Digest = true
+		}
+		targets = append(targets, target)
+	}
+	return notify.NewDispatcher(targets), nil
+}
+
+// notifyEvent 将历史记录转换为通知事件
+func notifyEvent(e history.Entry) notify.Event {
+	return notify.Event{
+		Event:    e.Event,
+		Time:     e.Time,
+		Record:   e.Record,
+		Provider: e.Provider,
+		Type:     e.Type,
+		OldIP:    e.OldValue,
+		NewIP:    e.NewValue,
+		Error:    e.Error,
+		DryRun:   e.DryRun,
+		Lag:      time.Duration(e.LagSeconds * float64(time.Second)),
+	}
+}
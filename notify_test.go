@@ -0,0 +1,66 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/notify"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWebhookFromConfig 从配置文件创建 webhook：url 和 headers 展开 ${VAR}，body 模板保持原样
+func TestWebhookFromConfig(t *testing.T) {
+	var gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		gotAuth, gotBody = r.Header.Get("Authorization"), r.URL.Path+" "+string(data)
+	}))
+	defer srv.Close()
+	t.Setenv("OD_HOOK", srv.URL)
+	t.Setenv("OD_TOKEN", "s3cret")
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	err := os.WriteFile(path, []byte(`provider: cloudflare
+domain: example.com
+subdomain: home
+update_interval_minutes: 5
+cloudflare:
+  api_token: token
+ip_sources:
+  - name: ipify
+    url: https://api.ipify.org
+    type: text
+notify:
+  - type: webhook
+    url: "${OD_HOOK}/hook"
+    method: PUT
+    headers:
+      Authorization: "Bearer ${OD_TOKEN}"
+    body: '{"text": {{json .Title}}, "cmd": "notify ${OD_TOKEN}"}'
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := newNotifier(cfg.Notify[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.Background(), notify.Event{Event: notify.EventIPChanged, Time: time.Now(), Record: "home.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, want expanded token", gotAuth)
+	}
+	if want := `/hook {"text": "[OpenDDNS] home.example.com: IP changed", "cmd": "notify ${OD_TOKEN}"}`; gotBody != want {
+		t.Errorf("request:\n got %s\nwant %s", gotBody, want)
+	}
+}
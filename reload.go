@@ -0,0 +1,129 @@
+package main
+
+import (
+	"OpenDDNS/internal/history"
+	"OpenDDNS/internal/logger"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// requestReload 请求重新加载配置，已有未处理的请求时合并
+func requestReload(reload chan<- string, reason string) {
+	select {
+	case reload <- reason:
+	default:
+	}
+}
+
+// watchConfig 监听配置文件所在目录，配置文件被写入、创建或替换时请求重新加载。
+// 监听目录而不是文件本身，以兼容编辑器的原子替换和 Kubernetes ConfigMap 的符号链接切换
+func watchConfig(path string, reload chan<- string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	name := filepath.Clean(path)
+	go func() {
+		defer watcher.Close()
+		// 编辑器保存时会连续产生多个事件，合并 500ms 内的事件
+		var debounce <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != name && filepath.Base(event.Name) != "..data" {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce = time.After(500 * time.Millisecond)
+				}
+			case <-debounce:
+				debounce = nil
+				requestReload(reload, "config file change")
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("Config watcher error: %v", err)
+			}
+		}
+	}()
+	logger.Info("Watching %s for changes.", path)
+	return nil
+}
+
+// reload 重新加载并校验配置，成功时替换配置与服务商并返回 true 以立即同步一轮；
+// 服务商、通知和历史文件全部创建成功后才切换日志设置和状态，任一步失败时保留旧配置并记录错误
+func (s *syncState) reload(configPath, reason string, ticker *time.Ticker) bool {
+	logger.Info("Reloading config (%s)...", reason)
+	cfg, err := readConfig(configPath)
+	if err != nil {
+		logger.With(logger.KeyError, err).Error("Config reload failed, keeping current config.")
+		return false
+	}
+	// 创建过程中的错误可能包含新凭证，暂时同时屏蔽新旧凭证
+	logger.SetSecrets(append(s.cfg.Secrets(), cfg.Secrets()...)...)
+	fail := func(err error) bool {
+		logger.With(logger.KeyError, err).Error("Config reload failed, keeping current config.")
+		logger.SetSecrets(s.cfg.Secrets()...)
+		return false
+	}
+	dnsProvider, err := buildProvider(cfg, s.dryRun)
+	if err != nil {
+		return fail(err)
+	}
+	notifier, err := buildNotifier(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	path := historyPath(configPath, cfg)
+	storeChanged := path != s.status.storePath()
+	var store *history.Store
+	if storeChanged && path != "" {
+		if store, err = history.Open(path); err != nil {
+			if notifier != nil {
+				notifier.Close(0)
+			}
+			return fail(err)
+		}
+	}
+
+	applyLogging(cfg)
+	if cfg.UpdateIntervalMinutes != s.cfg.UpdateIntervalMinutes {
+		ticker.Reset(updateInterval(cfg))
+		s.status.scheduled(time.Now().Add(updateInterval(cfg)))
+	}
+	if recordName(cfg) != recordName(s.cfg) || cfg.Provider != s.cfg.Provider {
+		// 暂停和 on_missing 状态只针对原来的记录，不能在新记录上删除占位记录
+		s.paused = false
+		s.missingRounds = nil
+		s.parked, s.parkedType, s.staleType = false, "", ""
+		s.lastRecordType = ""
+	}
+	if cfg.HTTP != s.cfg.HTTP {
+		logger.Warn("http settings changed, restart to apply them.")
+	}
+	if cfg.WatchConfig != s.cfg.WatchConfig {
+		logger.Warn("watch_config changed, restart to apply it.")
+	}
+	s.cfg = cfg
+	s.provider = dnsProvider
+	s.status.reset(cfg)
+	if storeChanged {
+		s.status.setStore(store)
+	}
+	s.status.setNotifier(notifier)
+	// 记录、来源或服务商可能已变化，强制下一轮重新同步
+	s.lastIP = ""
+	logger.Info("Config reloaded: %s.%s via %s", cfg.Subdomain, cfg.Domain, cfg.Provider)
+	return true
+}
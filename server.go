@@ -0,0 +1,100 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/dashboard"
+	"OpenDDNS/internal/logger"
+	"OpenDDNS/internal/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// listen 监听 host:port 或 unix:/path，unix socket 会先移除残留的 socket 文件，
+// 路径上是其它类型的文件时报错，避免配置写错时误删
+func listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if fi, err := os.Lstat(path); err == nil {
+			if fi.Mode().Type() != os.ModeSocket {
+				return nil, fmt.Errorf("%s exists and is not a unix socket", path)
+			}
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		// 只允许所有者和同组用户访问
+		if err := os.Chmod(path, 0660); err != nil {
+			ln.Close()
+			return nil, err
+		}
+		return ln, nil
+	}
+	return net.Listen("tcp", addr)
+}
+
+// startHTTPServer 按配置启动内置 HTTP 服务，ctx 取消时关闭；修改 http 配置需要重启生效
+func startHTTPServer(ctx context.Context, cfg config.HTTPConfig, status *daemonStatus, control chan<- string) error {
+	if cfg.Listen == "" {
+		return nil
+	}
+	handler, err := newHTTPHandler(cfg, status, control)
+	if err != nil {
+		return err
+	}
+	ln, err := listen(cfg.Listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("HTTP server stopped: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	logger.Info("HTTP server listening on %s.", cfg.Listen)
+	return nil
+}
+
+// newHTTPHandler 按配置注册指标、健康检查、API 和控制台路由
+func newHTTPHandler(cfg config.HTTPConfig, status *daemonStatus, control chan<- string) (http.Handler, error) {
+	mux := http.NewServeMux()
+	if cfg.Metrics {
+		mux.Handle("GET /metrics", metrics.Handler())
+	}
+	if cfg.Health {
+		mux.HandleFunc("GET /healthz", status.handleHealthz)
+		mux.HandleFunc("GET /readyz", status.handleReadyz)
+	}
+	if cfg.API {
+		api := &apiServer{token: cfg.Token, readOnly: cfg.ReadOnly, status: status, control: control}
+		api.register(mux, "/api/v1")
+	}
+	if cfg.Dashboard {
+		// 控制台使用独立的数据路径，可监听本机以外的地址，此时配置校验要求设置 token
+		ui := &apiServer{token: cfg.Token, readOnly: cfg.ReadOnly, status: status, control: control}
+		ui.register(mux, dashboard.APIPrefix)
+		handler, err := dashboard.Handler(cfg.ReadOnly, cfg.Token != "")
+		if err != nil {
+			return nil, err
+		}
+		mux.Handle("GET /", handler)
+	}
+	return mux, nil
+}
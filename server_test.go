@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnixKeepsRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("provider: cloudflare\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if ln, err := listen("unix:" + path); err == nil {
+		ln.Close()
+		t.Fatal("listen succeeded on a regular file, want error")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "provider: cloudflare\n" {
+		t.Fatalf("regular file was modified: %q, %v", data, err)
+	}
+}
+
+func TestListenUnixReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openddns.sock")
+	// 模拟上次退出时残留的 socket 文件
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listen("unix:" + path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0660 {
+		t.Errorf("socket mode = %v, want 0660", fi.Mode().Perm())
+	}
+}
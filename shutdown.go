@@ -0,0 +1,59 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/logger"
+	"OpenDDNS/internal/metrics"
+	"context"
+	"time"
+)
+
+// 未配置 shutdown_grace_seconds 时等待进行中更新的时长
+const defaultShutdownGrace = 30 * time.Second
+
+// 宽限期结束取消进行中的更新后，等待其返回的时长
+const cancelWait = 5 * time.Second
+
+// shutdownGrace 返回退出时等待进行中更新的宽限期
+func shutdownGrace(cfg *config.Config) time.Duration {
+	if cfg.ShutdownGraceSeconds > 0 {
+		return time.Duration(cfg.ShutdownGraceSeconds) * time.Second
+	}
+	return defaultShutdownGrace
+}
+
+// startRound 在后台执行一轮同步，结束后通过返回的 channel 交付结果
+func (s *syncState) startRound(ctx context.Context) <-chan int {
+	s.status.tick()
+	done := make(chan int, 1)
+	go func() {
+		result := s.runRound(ctx)
+		metrics.ObserveRound(roundLabel(result))
+		done <- result
+	}()
+	return done
+}
+
+// waitInFlight 收到退出信号后在宽限期内等待进行中的一轮同步结束，超时后调用 cancel 取消
+// 进行中的请求并短暂等待其返回；返回该轮结果以及是否按时完成，inflight 为 nil 表示没有进行中的同步
+func waitInFlight(inflight <-chan int, grace time.Duration, cancel context.CancelFunc) (int, bool) {
+	if inflight == nil {
+		return roundUnchanged, true
+	}
+	logger.Info("Shutting down, waiting up to %s for the in-flight update to finish (signal again to force quit)...", grace)
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case result := <-inflight:
+		return result, true
+	case <-timer.C:
+	}
+	logger.Error("In-flight update did not finish within %s, cancelling it.", grace)
+	cancel()
+	select {
+	case <-inflight:
+	case <-time.After(cancelWait):
+		logger.Warn("In-flight update did not stop within %s after cancellation, exiting anyway.", cancelWait)
+	}
+	return roundProviderFailed, false
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitInFlightFinishes(t *testing.T) {
+	inflight := make(chan int, 1)
+	inflight <- roundUpdated
+	result, finished := waitInFlight(inflight, time.Second, func() { t.Error("cancel called for a finished round") })
+	if !finished || result != roundUpdated {
+		t.Errorf("waitInFlight = %d, %v, want %d, true", result, finished, roundUpdated)
+	}
+}
+
+func TestWaitInFlightCancelsAfterGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	inflight := make(chan int, 1)
+	go func() {
+		// 模拟只在取消后才返回的请求
+		<-ctx.Done()
+		inflight <- roundProviderFailed
+	}()
+	start := time.Now()
+	_, finished := waitInFlight(inflight, 20*time.Millisecond, cancel)
+	if finished {
+		t.Error("finished = true, want false after the grace period")
+	}
+	if ctx.Err() == nil {
+		t.Error("in-flight round was not cancelled")
+	}
+	if elapsed := time.Since(start); elapsed >= cancelWait {
+		t.Errorf("waitInFlight took %s, want it to return once the round stops", elapsed)
+	}
+}
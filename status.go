@@ -0,0 +1,438 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/history"
+	ipfetcher "OpenDDNS/internal/ip_fetcher"
+	"OpenDDNS/internal/logger"
+	"OpenDDNS/internal/notify"
+	"OpenDDNS/internal/provider"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 主循环超过 2 个同步间隔再加该时长未开始新一轮时视为不健康
+const healthSlack = time.Minute
+
+// 内存中保留的最近事件数
+const historySize = 200
+
+// sourceStatus 单个 IP 源最近一次查询的结果和累计次数
+type sourceStatus struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	IP        string    `json:"ip,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Latency   float64   `json:"latency_seconds"`
+	LastCheck time.Time `json:"last_check"`
+	Successes int       `json:"successes"`
+	Failures  int       `json:"failures"`
+}
+
+// recordStatus 单条记录的同步状态
+type recordStatus struct {
+	Record     string    `json:"record"`
+	Type       string    `json:"type,omitempty"`
+	DetectedIP string    `json:"detected_ip,omitempty"`
+	PushedIP   string    `json:"pushed_ip,omitempty"`
+	InSync     bool      `json:"in_sync"`
+	Paused     bool      `json:"paused"`
+	LastError  string    `json:"last_error,omitempty"`
+	LastUpdate time.Time `json:"last_update,omitzero"`
+}
+
+// daemonStatus 主循环和同步状态，由 HTTP 服务并发读取
+type daemonStatus struct {
+	mu            sync.Mutex
+	interval      time.Duration
+	lastTick      time.Time
+	lastDetection time.Time
+	detectionOK   bool
+	nextRun       time.Time
+	record        recordStatus
+	sources       []sourceStatus
+	vote          string    // 最近一轮的投票判定方式
+	detectedAt    time.Time // 检测到当前地址的时间，用于计算更新延迟
+	provider      string
+	dryRun        bool
+	history       []history.Entry
+	store         *history.Store     // 未配置 history_file 时为 nil
+	notifier      *notify.Dispatcher // 未配置 notify 时为 nil
+	failing       bool               // 处于失败状态，恢复时发送 recovered
+	lastFailure   string             // 最近一次已通知的失败事件，持续同类失败不重复通知
+}
+
+func newDaemonStatus(cfg *config.Config, dryRun bool) *daemonStatus {
+	return &daemonStatus{
+		interval: updateInterval(cfg),
+		lastTick: time.Now(),
+		provider: cfg.Provider,
+		dryRun:   dryRun,
+		record:   recordStatus{Record: recordName(cfg)},
+	}
+}
+
+// reset 配置重新加载后更新同步间隔和服务商，记录变化时清空其状态
+func (s *daemonStatus) reset(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interval = updateInterval(cfg)
+	s.provider = cfg.Provider
+	if record := recordName(cfg); s.record.Record != record {
+		s.record = recordStatus{Record: record}
+	}
+}
+
+// setStore 替换历史文件并关闭之前的文件，store 为 nil 时只保留在内存中
+func (s *daemonStatus) setStore(store *history.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store != nil {
+		s.store.Close()
+	}
+	s.store = store
+}
+
+// storePath 返回当前历史文件路径，未配置时为空
+func (s *daemonStatus) storePath() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store == nil {
+		return ""
+	}
+	return s.store.Path()
+}
+
+// tick 主循环开始一轮同步
+func (s *daemonStatus) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastTick = time.Now()
+}
+
+// detected 记录一次检测的结果，ip 为空表示检测失败
+func (s *daemonStatus) detected(ip, recordType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastDetection = time.Now()
+	if ip == "" {
+		// 只在由成功变为失败时记录事件，避免持续失败时刷屏
+		if s.detectionOK || len(s.history) == 0 {
+			s.addEvent(history.Entry{Event: history.EventDetectionFailed, OldValue: s.record.DetectedIP})
+		}
+		s.detectionOK = false
+		return
+	}
+	s.detectionOK = true
+	s.record.Type = recordType
+	if ip != s.record.DetectedIP {
+		s.addEvent(history.Entry{Event: history.EventIPChanged, OldValue: s.record.DetectedIP, NewValue: ip})
+		s.detectedAt = time.Now()
+	}
+	s.record.DetectedIP = ip
+	s.record.InSync = ip == s.record.PushedIP
+	s.checkRecovered()
+}
+
+// pushed 记录一次推送的结果，changes 为服务商实际执行（dry-run 时为计划）的变更
+func (s *daemonStatus) pushed(ip string, changes []provider.Change, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.record.InSync = false
+		s.record.LastError = logger.Redact(err.Error())
+		// 部分失败时（如记录已创建但备注写入失败）一并记录服务商返回的变更
+		e := history.Entry{
+			Event:    history.EventUpdateFailed,
+			OldValue: s.record.PushedIP,
+			NewValue: ip,
+			Error:    s.record.LastError,
+		}
+		e.RecordID, e.Response = summarizeChanges(changes)
+		s.addEvent(e)
+		return
+	}
+	if len(changes) > 0 {
+		e := history.Entry{
+			Event:    history.EventUpdateSucceeded,
+			OldValue: changes[0].Old,
+			NewValue: ip,
+			DryRun:   s.dryRun,
+		}
+		e.RecordID, e.Response = summarizeChanges(changes)
+		if !s.detectedAt.IsZero() {
+			e.LagSeconds = time.Since(s.detectedAt).Seconds()
+		}
+		s.addEvent(e)
+	}
+	s.record.LastError = ""
+	if s.dryRun && len(changes) > 0 {
+		// 只是计划的变更，服务商上的记录仍未同步
+		s.record.InSync = false
+		return
+	}
+	s.record.PushedIP = ip
+	s.record.InSync = ip == s.record.DetectedIP
+	s.record.LastUpdate = time.Now()
+	s.checkRecovered()
+}
+
+// summarizeChanges 返回变更涉及的记录 ID（逗号分隔）和已脱敏的变更摘要
+func summarizeChanges(changes []provider.Change) (string, string) {
+	var ids, response []string
+	for _, c := range changes {
+		if c.RecordID != "" {
+			ids = append(ids, c.RecordID)
+		}
+		response = append(response, c.String())
+	}
+	return strings.Join(ids, ","), logger.Redact(strings.Join(response, "; "))
+}
+
+// checkRecovered 失败后检测成功且记录已同步时记录 recovered；调用方需持有 mu
+func (s *daemonStatus) checkRecovered() {
+	if s.failing && s.detectionOK && s.record.InSync {
+		s.addEvent(history.Entry{Event: history.EventRecovered, NewValue: s.record.DetectedIP})
+	}
+}
+
+// setNotifier 替换通知队列并启动每日摘要，之前的队列在等待未发送的通知后关闭；notifier 为 nil 时不发送通知
+func (s *daemonStatus) setNotifier(notifier *notify.Dispatcher) {
+	s.mu.Lock()
+	old := s.notifier
+	s.notifier = notifier
+	s.mu.Unlock()
+	if old != nil {
+		old.Close(notifyDrainTimeout)
+	}
+	if notifier != nil {
+		notifier.StartDigests(s.digestEvents)
+	}
+}
+
+// setPaused 记录是否暂停更新
+func (s *daemonStatus) setPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record.Paused = paused
+}
+
+// scheduled 记录下一次定时同步的时间
+func (s *daemonStatus) scheduled(next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRun = next
+}
+
+// observeSources 记录本轮各 IP 源的结果和投票判定方式，累计次数按来源名称保留
+func (s *daemonStatus) observeSources(results []ipfetcher.FetchResult, vote string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vote = vote
+	prev := make(map[string]sourceStatus, len(s.sources))
+	for _, src := range s.sources {
+		prev[src.Name] = src
+	}
+	now := time.Now()
+	sources := make([]sourceStatus, 0, len(results))
+	for _, r := range results {
+		src := prev[r.Source]
+		src.Name = r.Source
+		src.Healthy = r.Err == nil
+		src.IP = r.IP
+		src.Error = ""
+		src.Latency = r.Latency.Seconds()
+		src.LastCheck = now
+		if r.Err != nil {
+			src.Error = logger.Redact(r.Err.Error())
+			src.Failures++
+		} else {
+			src.Successes++
+		}
+		sources = append(sources, src)
+	}
+	s.sources = sources
+}
+
+// addEvent 补全记录、服务商和本轮各来源的结果后保存事件，内存中超过 historySize 时丢弃最早的；
+// 调用方需持有 mu
+func (s *daemonStatus) addEvent(e history.Entry) {
+	e.Time = time.Now()
+	e.Record = s.record.Record
+	e.Provider = s.provider
+	e.Type = s.record.Type
+	e.Vote = s.vote
+	for _, src := range s.sources {
+		e.Sources = append(e.Sources, history.Source{Name: src.Name, IP: src.IP, Error: src.Error})
+	}
+	s.history = append(s.history, e)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+	if s.store != nil {
+		if err := s.store.Append(e); err != nil {
+			logger.Warn("Failed to write history: %v", err)
+		}
+	}
+
+	send := true
+	switch e.Event {
+	case history.EventUpdateFailed, history.EventDetectionFailed:
+		send = !s.failing || s.lastFailure != e.Event
+		s.failing, s.lastFailure = true, e.Event
+	case history.EventRecovered:
+		s.failing, s.lastFailure = false, ""
+	}
+	if send && s.notifier != nil {
+		s.notifier.Send(notifyEvent(e))
+	}
+}
+
+// recentEvents 返回满足条件的事件，新的在前；配置了 history_file 时从文件读取
+func (s *daemonStatus) recentEvents(f history.Filter) ([]history.Entry, error) {
+	var entries []history.Entry
+	if path := s.storePath(); path != "" {
+		var err error
+		if entries, err = history.Read(path, f); err != nil {
+			return nil, err
+		}
+	} else {
+		s.mu.Lock()
+		for _, e := range s.history {
+			if f.Match(e) {
+				entries = append(entries, e)
+			}
+		}
+		s.mu.Unlock()
+		if f.Limit > 0 && len(entries) > f.Limit {
+			entries = entries[len(entries)-f.Limit:]
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// digestEvents 返回 [since, until) 内的事件，按时间从旧到新，用于每日摘要
+func (s *daemonStatus) digestEvents(since, until time.Time) ([]notify.Event, error) {
+	entries, err := s.recentEvents(history.Filter{Since: since, Until: until})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]notify.Event, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		events = append(events, notifyEvent(entries[i]))
+	}
+	return events, nil
+}
+
+// statusResponse /api/v1/status 的响应
+type statusResponse struct {
+	DryRun        bool              `json:"dry_run"`
+	DetectionOK   bool              `json:"detection_ok"`
+	LastDetection time.Time         `json:"last_detection,omitzero"`
+	NextRun       time.Time         `json:"next_run,omitzero"`
+	Detected      map[string]string `json:"detected"`       // 按地址族
+	Vote          string            `json:"vote,omitempty"` // none、single、majority 或 priority
+	Records       []recordStatus    `json:"records"`
+	Sources       []sourceStatus    `json:"sources"`
+}
+
+// snapshot 返回完整状态
+func (s *daemonStatus) snapshot() statusResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	detected := make(map[string]string)
+	if s.record.DetectedIP != "" {
+		detected[ipFamily(s.record.Type)] = s.record.DetectedIP
+	}
+	return statusResponse{
+		DryRun:        s.dryRun,
+		DetectionOK:   s.detectionOK,
+		LastDetection: s.lastDetection,
+		NextRun:       s.nextRun,
+		Detected:      detected,
+		Vote:          s.vote,
+		Records:       []recordStatus{s.record},
+		Sources:       append([]sourceStatus{}, s.sources...),
+	}
+}
+
+// recordName 返回记录名称，用于校验 API 路径
+func (s *daemonStatus) recordName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.record.Record
+}
+
+// healthResponse /healthz 的响应
+type healthResponse struct {
+	Healthy    bool      `json:"healthy"`
+	LastTick   time.Time `json:"last_tick"`
+	MaxAgeSecs float64   `json:"max_age_seconds"`
+}
+
+// readyResponse /readyz 的响应
+type readyResponse struct {
+	Ready         bool           `json:"ready"`
+	DryRun        bool           `json:"dry_run"`
+	DetectionOK   bool           `json:"detection_ok"`
+	LastDetection time.Time      `json:"last_detection,omitzero"`
+	Records       []recordStatus `json:"records"`
+}
+
+// health 主循环在 2 个同步间隔加 healthSlack 内开始过一轮即为健康
+func (s *daemonStatus) health() healthResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maxAge := 2*s.interval + healthSlack
+	return healthResponse{
+		Healthy:    time.Since(s.lastTick) <= maxAge,
+		LastTick:   s.lastTick,
+		MaxAgeSecs: maxAge.Seconds(),
+	}
+}
+
+// readiness 最近一次检测成功且所有未暂停的记录已同步即为就绪
+func (s *daemonStatus) readiness() readyResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readyResponse{
+		Ready:         s.detectionOK && (s.record.InSync || s.record.Paused),
+		DryRun:        s.dryRun,
+		DetectionOK:   s.detectionOK,
+		LastDetection: s.lastDetection,
+		Records:       []recordStatus{s.record},
+	}
+}
+
+// handleHealthz 健康时返回 200，否则 503
+func (s *daemonStatus) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	resp := s.health()
+	writeStatusJSON(w, resp.Healthy, resp)
+}
+
+// handleReadyz 就绪时返回 200，否则 503
+func (s *daemonStatus) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	resp := s.readiness()
+	writeStatusJSON(w, resp.Ready, resp)
+}
+
+func writeStatusJSON(w http.ResponseWriter, ok bool, v any) {
+	code := http.StatusOK
+	if !ok {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, v)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
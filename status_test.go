@@ -0,0 +1,116 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/history"
+	"OpenDDNS/internal/provider"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHealthz(t *testing.T) {
+	cfg := &config.Config{Provider: "cloudflare", Domain: "example.com", Subdomain: "home", UpdateIntervalMinutes: 5}
+	status := newDaemonStatus(cfg, false)
+	handler, err := newHTTPHandler(config.HTTPConfig{Listen: "127.0.0.1:0", Health: true}, status, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(handler, http.MethodGet, "/healthz", ""); rec.Code != http.StatusOK {
+		t.Errorf("healthz after start = %d, want %d", rec.Code, http.StatusOK)
+	}
+	// 主循环超过 2 个同步间隔加 healthSlack 未开始新一轮
+	status.mu.Lock()
+	status.lastTick = time.Now().Add(-2*updateInterval(cfg) - healthSlack - time.Second)
+	status.mu.Unlock()
+	if rec := serve(handler, http.MethodGet, "/healthz", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("healthz with a stalled loop = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	changes := []provider.Change{{Action: provider.ActionUpdate, Type: "A", Old: "198.51.100.1", New: "203.0.113.7"}}
+	tests := []struct {
+		name   string
+		dryRun bool
+		round  func(s *daemonStatus)
+		want   bool
+		pushed string
+	}{
+		{"before first round", false, func(s *daemonStatus) {}, false, ""},
+		{"detection failed", false, func(s *daemonStatus) { s.detected("", "") }, false, ""},
+		{"detected, not pushed", false, func(s *daemonStatus) { s.detected("203.0.113.7", "A") }, false, ""},
+		{"updated", false, func(s *daemonStatus) {
+			s.detected("203.0.113.7", "A")
+			s.pushed("203.0.113.7", changes, nil)
+		}, true, "203.0.113.7"},
+		{"already up to date", false, func(s *daemonStatus) {
+			s.detected("203.0.113.7", "A")
+			s.pushed("203.0.113.7", nil, nil)
+		}, true, "203.0.113.7"},
+		{"update failed", false, func(s *daemonStatus) {
+			s.detected("203.0.113.7", "A")
+			s.pushed("203.0.113.7", nil, provider.ErrNotOwned)
+		}, false, ""},
+		{"paused", false, func(s *daemonStatus) {
+			s.detected("203.0.113.7", "A")
+			s.setPaused(true)
+		}, true, ""},
+		{"dry-run planned update", true, func(s *daemonStatus) {
+			s.detected("203.0.113.7", "A")
+			s.pushed("203.0.113.7", changes, nil)
+		}, false, ""},
+		{"dry-run already up to date", true, func(s *daemonStatus) {
+			s.detected("203.0.113.7", "A")
+			s.pushed("203.0.113.7", nil, nil)
+		}, true, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Provider: "cloudflare", Domain: "example.com", Subdomain: "home", UpdateIntervalMinutes: 5}
+			status := newDaemonStatus(cfg, tt.dryRun)
+			tt.round(status)
+			handler, err := newHTTPHandler(config.HTTPConfig{Listen: "127.0.0.1:0", Health: true}, status, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := serve(handler, http.MethodGet, "/readyz", "")
+			var resp readyResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			wantCode := http.StatusServiceUnavailable
+			if tt.want {
+				wantCode = http.StatusOK
+			}
+			if rec.Code != wantCode || resp.Ready != tt.want {
+				t.Errorf("readyz = %d ready=%v, want %d ready=%v", rec.Code, resp.Ready, wantCode, tt.want)
+			}
+			if resp.DryRun != tt.dryRun {
+				t.Errorf("dry_run = %v, want %v", resp.DryRun, tt.dryRun)
+			}
+			if got := resp.Records[0].PushedIP; got != tt.pushed {
+				t.Errorf("pushed_ip = %q, want %q", got, tt.pushed)
+			}
+		})
+	}
+}
+
+func TestPushedPartialFailure(t *testing.T) {
+	cfg := &config.Config{Provider: "aliyun", Domain: "example.com", Subdomain: "home", UpdateIntervalMinutes: 5}
+	status := newDaemonStatus(cfg, false)
+	status.detected("203.0.113.7", "A")
+	// 记录已创建但备注写入失败
+	changes := []provider.Change{{Action: provider.ActionCreate, Name: "home.example.com", Type: "A", RecordID: "new-1", New: "203.0.113.7"}}
+	status.pushed("203.0.113.7", changes, errors.New("write remark: Throttling.User"))
+
+	e := status.history[len(status.history)-1]
+	if e.Event != history.EventUpdateFailed || e.RecordID != "new-1" || e.Response != changes[0].String() {
+		t.Errorf("event = %s, record_id %q, response %q, want update_failed with the create", e.Event, e.RecordID, e.Response)
+	}
+	if status.snapshot().Records[0].InSync {
+		t.Error("record reported in sync after a failed push")
+	}
+}
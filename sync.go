@@ -0,0 +1,261 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	ipfetcher "OpenDDNS/internal/ip_fetcher"
+	"OpenDDNS/internal/logger"
+	"OpenDDNS/internal/metrics"
+	"OpenDDNS/internal/provider"
+	"context"
+	"strings"
+	"time"
+)
+
+// 单轮同步的结果
+const (
+	roundUnchanged = iota
+	roundUpdated
+	roundDetectionFailed
+	roundProviderFailed
+)
+
+// 进程退出码
+const (
+	exitUnchanged       = 0
+	exitFailure         = 1
+	exitDetectionFailed = 2
+	exitProviderFailed  = 3
+	exitUpdated         = 10
+)
+
+// exitCode 将单轮结果映射为进程退出码，dry-run 没有实际修改记录，不返回 exitUpdated
+func exitCode(result int, dryRun bool) int {
+	switch result {
+	case roundUpdated:
+		if dryRun {
+			return exitUnchanged
+		}
+		return exitUpdated
+	case roundDetectionFailed:
+		return exitDetectionFailed
+	case roundProviderFailed:
+		return exitProviderFailed
+	default:
+		return exitUnchanged
+	}
+}
+
+// roundLabel 返回单轮结果的指标标签
+func roundLabel(result int) string {
+	switch result {
+	case roundUpdated:
+		return metrics.RoundUpdated
+	case roundDetectionFailed:
+		return metrics.RoundDetectionFailed
+	case roundProviderFailed:
+		return metrics.RoundProviderFailed
+	default:
+		return metrics.RoundUnchanged
+	}
+}
+
+// ipFamily 返回记录类型对应的地址族
+func ipFamily(recordType string) string {
+	if recordType == "AAAA" {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// syncState 主循环在各轮之间保留的状态
+type syncState struct {
+	cfg      *config.Config
+	provider provider.DNSProvider
+	dryRun   bool
+	status   *daemonStatus
+	paused   bool // 通过 API 暂停，只检测不更新
+
+	lastIP         string
+	detectedIP     string // 最近一次检测到的地址，不论是否已同步
+	lastRecordType string
+	// on_missing 状态：各记录类型连续未获取到地址的轮数，以及是否已按策略处理
+	missingRounds map[string]int
+	parked        bool
+	parkedType    string
+	// auto 模式下地址族切换后仍留在服务商上的旧记录类型，按 on_missing 处理后清空
+	staleType string
+}
+
+// runRound 执行一轮 IP 检测与记录同步，ctx 取消时中止进行中的请求
+func (s *syncState) runRound(ctx context.Context) int {
+	cfg := s.cfg
+	log := recordLogger(cfg)
+	results := fetchAll(ctx, cfg.IPSources, networkTypeFor(cfg.RecordType))
+	if ctx.Err() != nil {
+		// 退出时被取消，不计入 on_missing 的失败轮数
+		log.Warn("Round cancelled during IP detection.")
+		return roundDetectionFailed
+	}
+	newIP, vote := voteIP(results)
+	s.status.observeSources(results, vote)
+	if newIP == "" {
+		log.Warn("Failed to determine public IP.")
+		s.status.detected("", "")
+		missingType := ipfetcher.DetermineRecordType("", cfg.RecordType)
+		if missingType == "" {
+			missingType = s.lastRecordType
+		}
+		s.missed(missingType)
+		if s.paused {
+			// 暂停时不执行 on_missing，恢复后仍未获取到地址才处理
+			log.Debug("Record paused, skipping on_missing policy.")
+		} else if !s.parked {
+			s.parked, s.parkedType = s.applyOnMissing(ctx, missingType)
+			if s.parked {
+				s.lastIP = ""
+			}
+		}
+		return roundDetectionFailed
+	}
+	detectedType := ipfetcher.DetermineRecordType(newIP, cfg.RecordType)
+	delete(s.missingRounds, detectedType)
+	s.checkStale(ctx, detectedType)
+	if newIP == s.lastIP {
+		log.Debug("IP not changed: %s", newIP)
+		s.status.detected(newIP, s.lastRecordType)
+		return roundUnchanged
+	}
+	log = log.With(logger.KeyIP, newIP, logger.KeyOldIP, s.lastIP)
+	log.Info("Detected public IP.")
+
+	// 确定DNS记录类型
+	recordType := detectedType
+	if recordType == "" {
+		log.Error("Invalid IP address format: %s", newIP)
+		s.status.detected("", "")
+		return roundDetectionFailed
+	}
+	log.Debug("Using DNS record type: %s", recordType)
+	s.status.detected(newIP, recordType)
+	if newIP != s.detectedIP {
+		// 只在检测结果变化时刷新，更新失败重试时不影响 IP 变化时间
+		metrics.ObserveIPChange(recordName(cfg), ipFamily(recordType), newIP)
+		s.detectedIP = newIP
+	}
+
+	if s.paused {
+		log.Info("Record paused, skipping update.")
+		return roundUnchanged
+	}
+
+	if s.parked && s.parkedType != "" && s.parkedType != recordType {
+		// 清除 on_missing 写入的占位记录（如 CNAME）
+		if _, err := s.provider.DeleteRecord(ctx, s.parkedType); err != nil {
+			log.With(logger.KeyError, err).Error("Error removing fallback %s record.", s.parkedType)
+			s.status.pushed(newIP, nil, err)
+			return roundProviderFailed
+		}
+	}
+
+	start := time.Now()
+	changes, err := s.provider.UpdateRecord(ctx, newIP, recordType)
+	log = log.With(logger.KeyDuration, time.Since(start))
+	metrics.ObserveUpdate(recordName(cfg), err)
+	s.status.pushed(newIP, changes, err)
+	if err != nil {
+		log.With(logger.KeyError, err).Error("Error updating DNS record.")
+		return roundProviderFailed
+	}
+	s.lastIP = newIP
+	s.lastRecordType = recordType
+	s.parked = false
+	switch {
+	case len(changes) == 0:
+		log.Info("DNS record already up-to-date.")
+		return roundUnchanged
+	case s.dryRun:
+		log.Info("Dry-run finished, DNS record not modified.")
+	default:
+		log.Info("DNS record updated successfully.")
+	}
+	return roundUpdated
+}
+
+// missed 记录 recordType 又有一轮未获取到地址
+func (s *syncState) missed(recordType string) {
+	if s.missingRounds == nil {
+		s.missingRounds = make(map[string]int)
+	}
+	s.missingRounds[recordType]++
+}
+
+// checkStale 检测到的地址族与已发布的记录类型不同时（auto 模式下 IPv6 丢失后回落到 IPv4 等），
+// 把原记录类型计为未获取到地址，连续轮数达到 on_missing 的要求后按策略处理
+func (s *syncState) checkStale(ctx context.Context, detectedType string) {
+	if detectedType == "" {
+		return
+	}
+	if s.lastRecordType != "" && s.lastRecordType != detectedType && !s.parked {
+		s.staleType = s.lastRecordType
+	}
+	if s.staleType == "" || s.staleType == detectedType {
+		// 原地址族已恢复
+		s.staleType = ""
+		return
+	}
+	s.missed(s.staleType)
+	if s.paused {
+		recordLogger(s.cfg).Debug("Record paused, skipping on_missing policy.")
+		return
+	}
+	if handled, fallbackType := s.applyOnMissing(ctx, s.staleType); handled {
+		if fallbackType == detectedType {
+			// 占位记录覆盖了当前地址族的记录，强制本轮重新同步
+			s.lastIP = ""
+		}
+		delete(s.missingRounds, s.staleType)
+		s.staleType = ""
+	}
+}
+
+// applyOnMissing 连续多轮未获取到地址时按 on_missing 策略处理记录
+// 返回是否已处理，以及当前存在的占位记录类型（delete 时为空）
+func (s *syncState) applyOnMissing(ctx context.Context, recordType string) (bool, string) {
+	policy := s.cfg.OnMissing
+	log := recordLogger(s.cfg)
+	after := policy.AfterRounds
+	if after <= 0 {
+		after = 3
+	}
+	if s.missingRounds[recordType] < after || recordType == "" {
+		return false, ""
+	}
+	switch strings.ToLower(policy.Action) {
+	case "delete":
+		log.Warn("No %s address for %d rounds, deleting record.", recordType, s.missingRounds[recordType])
+		if _, err := s.provider.DeleteRecord(ctx, recordType); err != nil {
+			log.With(logger.KeyError, err).Error("Error deleting DNS record.")
+			return false, ""
+		}
+		return true, ""
+	case "set":
+		fallbackType := strings.ToUpper(policy.Type)
+		if fallbackType == "" {
+			fallbackType = recordType
+		}
+		log.Warn("No %s address for %d rounds, setting fallback %s %s.", recordType, s.missingRounds[recordType], fallbackType, policy.Value)
+		if fallbackType != recordType {
+			if _, err := s.provider.DeleteRecord(ctx, recordType); err != nil {
+				log.With(logger.KeyError, err).Error("Error deleting DNS record.")
+				return false, ""
+			}
+		}
+		if _, err := s.provider.UpdateRecord(ctx, policy.Value, fallbackType); err != nil {
+			log.With(logger.KeyError, err).Error("Error setting fallback record.")
+			return false, ""
+		}
+		return true, fallbackType
+	default: // keep
+		return false, ""
+	}
+}
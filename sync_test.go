@@ -0,0 +1,97 @@
+package main
+
+import (
+	"OpenDDNS/internal/config"
+	"OpenDDNS/internal/provider"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		result int
+		dryRun bool
+		want   int
+	}{
+		{roundUnchanged, false, exitUnchanged},
+		{roundUpdated, false, exitUpdated},
+		{roundDetectionFailed, false, exitDetectionFailed},
+		{roundProviderFailed, false, exitProviderFailed},
+		{roundUnchanged, true, exitUnchanged},
+		{roundUpdated, true, exitUnchanged},
+		{roundDetectionFailed, true, exitDetectionFailed},
+		{roundProviderFailed, true, exitProviderFailed},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.result, tt.dryRun); got != tt.want {
+			t.Errorf("exitCode(%d, dryRun=%v) = %d, want %d", tt.result, tt.dryRun, got, tt.want)
+		}
+	}
+}
+
+// fakeProvider 记录 UpdateRecord/DeleteRecord 调用的服务商
+type fakeProvider struct {
+	provider.DNSProvider
+	calls []string
+}
+
+func (f *fakeProvider) UpdateRecord(ctx context.Context, ip string, recordType string) ([]provider.Change, error) {
+	f.calls = append(f.calls, "update "+recordType+" "+ip)
+	return []provider.Change{{Action: provider.ActionUpdate, Type: recordType, New: ip}}, nil
+}
+
+func (f *fakeProvider) DeleteRecord(ctx context.Context, recordType string) ([]provider.Change, error) {
+	f.calls = append(f.calls, "delete "+recordType)
+	return []provider.Change{{Action: provider.ActionDelete, Type: recordType}}, nil
+}
+
+// TestRunRoundFamilyChange auto 模式下 IPv6 丢失、回落到 IPv4 时，AAAA 记录按 on_missing 处理
+func TestRunRoundFamilyChange(t *testing.T) {
+	ip := "2001:db8::1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, ip)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		Provider:   "cloudflare",
+		Domain:     "example.com",
+		Subdomain:  "home",
+		RecordType: "auto",
+		IPSources:  []config.IPSrc{{Name: "stub", URL: srv.URL, Type: "text"}},
+		OnMissing:  config.OnMissingConfig{Action: "delete", AfterRounds: 2},
+	}
+	p := &fakeProvider{}
+	s := &syncState{cfg: cfg, provider: p, status: newDaemonStatus(cfg, false)}
+	rounds := []struct {
+		ip   string
+		want int
+	}{
+		{"2001:db8::1", roundUpdated},
+		{"192.0.2.1", roundUpdated},   // AAAA 第 1 轮缺失
+		{"192.0.2.1", roundUnchanged}, // 第 2 轮，删除 AAAA
+		{"192.0.2.1", roundUnchanged},
+		{"2001:db8::1", roundUpdated}, // IPv6 恢复后 A 成为旧记录
+		{"2001:db8::1", roundUnchanged},
+	}
+	for i, r := range rounds {
+		ip = r.ip
+		if got := s.runRound(context.Background()); got != r.want {
+			t.Errorf("round %d: result = %d, want %d", i+1, got, r.want)
+		}
+	}
+	want := []string{
+		"update AAAA 2001:db8::1",
+		"update A 192.0.2.1",
+		"delete AAAA",
+		"update AAAA 2001:db8::1",
+		"delete A",
+	}
+	if strings.Join(p.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("provider calls:\n%s\nwant:\n%s", strings.Join(p.calls, "\n"), strings.Join(want, "\n"))
+	}
+}